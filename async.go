@@ -0,0 +1,252 @@
+package gocassa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/gocql/gocql"
+)
+
+const defaultAsyncConcurrency = 32
+
+// ErrExecutorClosed is returned by futures started after the query executor
+// was closed.
+var ErrExecutorClosed = errors.New("Query executor is closed")
+
+// AsyncExecutor is implemented by query executors which run asynchronous
+// queries, such as those started by RunnableQuery.Async, in a bounded worker
+// pool. Close waits for any work started with Go to finish.
+//
+// Asynchronous queries against executors which do not implement AsyncExecutor
+// are run in their own goroutine.
+type AsyncExecutor interface {
+	// Go runs fn in the worker pool, blocking until a worker is available. An
+	// error is returned if ctx is done first or the executor is closed.
+	Go(ctx context.Context, fn func()) error
+}
+
+// asyncPool limits the number of goroutines running asynchronous queries and
+// keeps track of them so that closing the executor can wait for them.
+type asyncPool struct {
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	mtx    sync.Mutex
+	closed bool
+}
+
+func newAsyncPool(size int) *asyncPool {
+	if size <= 0 {
+		size = defaultAsyncConcurrency
+	}
+
+	return &asyncPool{
+		sem: make(chan struct{}, size),
+	}
+}
+
+func (p *asyncPool) Go(ctx context.Context, fn func()) error {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.mtx.Lock()
+	if p.closed {
+		p.mtx.Unlock()
+		<-p.sem
+		return ErrExecutorClosed
+	}
+	p.wg.Add(1)
+	p.mtx.Unlock()
+
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		fn()
+	}()
+
+	return nil
+}
+
+// close stops any new work from starting and waits for running work to finish
+func (p *asyncPool) close() {
+	p.mtx.Lock()
+	p.closed = true
+	p.mtx.Unlock()
+
+	p.wg.Wait()
+}
+
+// runAsync runs fn using the worker pool of the executor if it has one
+func runAsync(ctx context.Context, qe QueryExecutor, fn func()) error {
+	if async, ok := qe.(AsyncExecutor); ok {
+		return async.Go(ctx, fn)
+	}
+
+	go fn()
+	return nil
+}
+
+// A Future is the pending result of a query started with RunnableQuery.Async
+// or RunnableQueries.Async. The results can be read once Done is closed, the
+// Wait and Scan methods block until then.
+type Future struct {
+	done    chan struct{}
+	results [][]map[string]interface{}
+	errs    []error
+	err     error
+}
+
+func newFuture(n int) *Future {
+	return &Future{
+		done:    make(chan struct{}),
+		results: make([][]map[string]interface{}, n),
+		errs:    make([]error, n),
+	}
+}
+
+// Done returns a channel which is closed once every query has finished
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until every query has finished and returns the error of the
+// query. For a future of several queries a MultiError identifying the failed
+// queries is returned.
+func (f *Future) Wait() error {
+	<-f.done
+	return f.err
+}
+
+// Scan waits for the query to finish and copies its rows into the slice
+// pointed at by dest, like RunnableQuery.Scan. For a future of several
+// queries the rows of the first query are used, see ScanAt.
+func (f *Future) Scan(dest interface{}) error {
+	return f.ScanAt(0, dest)
+}
+
+// ScanOne waits for the query to finish and copies its first row into dest,
+// like RunnableQuery.ScanOne. gocql.ErrNotFound is returned if there are no
+// rows.
+func (f *Future) ScanOne(dest interface{}) error {
+	<-f.done
+	if len(f.errs) == 0 {
+		return gocql.ErrNotFound
+	}
+	if f.errs[0] != nil {
+		return f.errs[0]
+	}
+	if len(f.results[0]) == 0 {
+		return gocql.ErrNotFound
+	}
+
+	return decodeResult(f.results[0][0], dest)
+}
+
+// ScanAt waits for the queries to finish and copies the rows of the query at
+// index i into the slice pointed at by dest. The error of that query is
+// returned if it failed.
+func (f *Future) ScanAt(i int, dest interface{}) error {
+	<-f.done
+	if i < 0 || i >= len(f.results) {
+		return errors.New("Future query index out of range")
+	}
+	if f.errs[i] != nil {
+		return f.errs[i]
+	}
+
+	rows := f.results[i]
+	if rows == nil {
+		rows = []map[string]interface{}{}
+	}
+	return decodeResult(rows, dest)
+}
+
+// Async starts executing the query in the background and returns a future of
+// its result. Selects are read using Query so their rows can be scanned from
+// the future, any other query is executed using Execute.
+//
+// Async returns immediately, if the worker pool of the executor is full the
+// query waits for a worker in the background.
+func (q RunnableQuery) Async(ctx context.Context) *Future {
+	f := newFuture(1)
+
+	go func() {
+		err := runAsync(ctx, q.Executor, func() {
+			f.results[0], f.errs[0] = runQuery(ctx, q)
+			f.err = f.errs[0]
+			close(f.done)
+		})
+		if err != nil {
+			f.errs[0], f.err = err, err
+			close(f.done)
+		}
+	}()
+
+	return f
+}
+
+// Async starts executing each of the queries concurrently in the background
+// and returns a future which is done once all of them have finished. The rows
+// of each query can be read using ScanAt.
+//
+// Async returns immediately, the queries are handed to the worker pool of the
+// executor in order by a background goroutine.
+func (qs RunnableQueries) Async(ctx context.Context) *Future {
+	f := newFuture(len(qs.Queries))
+
+	go func() {
+		var wg sync.WaitGroup
+		for i := range qs.Queries {
+			q := qs.query(i)
+			i := i
+
+			wg.Add(1)
+			err := runAsync(ctx, q.Executor, func() {
+				defer wg.Done()
+				f.results[i], f.errs[i] = runQuery(ctx, q)
+			})
+			if err != nil {
+				f.errs[i] = err
+				wg.Done()
+			}
+		}
+		wg.Wait()
+
+		var multiErr MultiError
+		for i, err := range f.errs {
+			if err != nil {
+				multiErr.Errors = append(multiErr.Errors, QueryError{Index: i, Err: err})
+			}
+		}
+		if len(multiErr.Errors) > 0 {
+			f.err = multiErr
+		}
+		close(f.done)
+	}()
+
+	return f
+}
+
+// runQuery reads the rows of select queries and executes any other query
+func runQuery(ctx context.Context, q RunnableQuery) ([]map[string]interface{}, error) {
+	if isSelect(q.Query) {
+		return q.Executor.QueryContext(ctx, q.Query)
+	}
+
+	return nil, q.Executor.ExecuteContext(ctx, q.Query)
+}
+
+func isSelect(query QueryGenerator) bool {
+	if q, ok := query.(Query); ok {
+		return q.queryType == SelectQueryType
+	}
+
+	stmt, _ := query.GenerateStatement()
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(stmt)), "SELECT")
+}
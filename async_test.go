@@ -0,0 +1,131 @@
+package gocassa
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnableQueryAsync(t *testing.T) {
+	qe := NewMemoryExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id")
+
+	assert.Nil(t, tbl.Set(memoryDocument{ID: "a", Amount: 1}).Async(context.Background()).Wait())
+	assert.Nil(t, tbl.Set(memoryDocument{ID: "b", Amount: 2}).Async(context.Background()).Wait())
+
+	one := tbl.Read("a").Async(context.Background())
+	all := tbl.List().Async(context.Background())
+	missing := tbl.Read("x").Async(context.Background())
+
+	<-one.Done()
+	doc := memoryDocument{}
+	assert.Nil(t, one.ScanOne(&doc))
+	assert.Equal(t, 1, doc.Amount)
+
+	docs := []memoryDocument{}
+	assert.Nil(t, all.Scan(&docs))
+	assert.Len(t, docs, 2)
+
+	assert.NotNil(t, missing.ScanOne(&doc))
+}
+
+func TestRunnableQueriesAsync(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+	other := NewTable(k, "other", Document{}, []string{"fielda"}, nil, nil)
+
+	qe.ExpectSelect("test").Where("fielda", "a").Return([]map[string]interface{}{{"fielda": "a", "fieldb": "b"}})
+	qe.ExpectSelect("other").Where("fielda", "a").ReturnError(errors.New("Failed"))
+
+	f := MultiQuery(
+		tbl.Where(Eq("fielda", "a")).Read(),
+		other.Where(Eq("fielda", "a")).Read(),
+	).Async(context.Background())
+
+	err := f.Wait()
+	multiErr, ok := err.(MultiError)
+	if assert.True(t, ok) && assert.Len(t, multiErr.Errors, 1) {
+		assert.Equal(t, 1, multiErr.Errors[0].Index)
+	}
+
+	docs := []Document{}
+	assert.Nil(t, f.ScanAt(0, &docs))
+	assert.Equal(t, []Document{{FieldA: "a", FieldB: "b"}}, docs)
+	assert.EqualError(t, f.ScanAt(1, &docs), "Failed")
+	assert.NotNil(t, f.ScanAt(2, &docs))
+	qe.AssertExpectations(t)
+}
+
+// blockingExecutor holds every query until release is closed and runs at most
+// one asynchronous query at a time
+type blockingExecutor struct {
+	ExecutorWrapper
+	pool    *asyncPool
+	release chan struct{}
+}
+
+func (qe blockingExecutor) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	<-qe.release
+	return qe.Next.ExecuteContext(ctx, query)
+}
+
+func (qe blockingExecutor) Go(ctx context.Context, fn func()) error {
+	return qe.pool.Go(ctx, fn)
+}
+
+func TestRunnableQueriesAsync_fullPool(t *testing.T) {
+	qe := blockingExecutor{
+		ExecutorWrapper: ExecutorWrapper{Next: NewMemoryExecutor()},
+		pool:            newAsyncPool(1),
+		release:         make(chan struct{}),
+	}
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id")
+
+	started := make(chan *Future)
+	go func() {
+		started <- MultiQuery(
+			tbl.Set(memoryDocument{ID: "a"}),
+			tbl.Set(memoryDocument{ID: "b"}),
+			tbl.Set(memoryDocument{ID: "c"}),
+		).Async(context.Background())
+	}()
+
+	var f *Future
+	select {
+	case f = <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Async blocked while the pool was full")
+	}
+	one := tbl.Set(memoryDocument{ID: "d"}).Async(context.Background())
+
+	close(qe.release)
+	assert.Nil(t, f.Wait())
+	assert.Nil(t, one.Wait())
+}
+
+func TestAsyncPool(t *testing.T) {
+	pool := newAsyncPool(1)
+
+	release := make(chan struct{})
+	finished := false
+	assert.Nil(t, pool.Go(context.Background(), func() {
+		<-release
+		finished = true
+	}))
+
+	// The pool is full so Go blocks until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, pool.Go(ctx, func() {}))
+
+	go close(release)
+	pool.close()
+	assert.True(t, finished, "close waits for running work")
+	assert.Equal(t, ErrExecutorClosed, pool.Go(context.Background(), func() {}))
+}
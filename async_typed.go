@@ -0,0 +1,65 @@
+//go:build go1.18
+// +build go1.18
+
+package gocassa
+
+import (
+	"context"
+)
+
+// TypedFuture wraps a Future whose rows are documents of type T so that the
+// results are read as T rather than scanned into interface{} values. The
+// methods of the embedded Future remain available.
+type TypedFuture[T any] struct {
+	*Future
+}
+
+// NewTypedFuture wraps the future so that its rows are read as documents of
+// type T
+func NewTypedFuture[T any](f *Future) *TypedFuture[T] {
+	return &TypedFuture[T]{Future: f}
+}
+
+// Result waits for the query to finish and returns its documents. For a
+// future of several queries the documents of the first query are returned,
+// see ResultAt.
+func (f *TypedFuture[T]) Result() ([]T, error) {
+	return f.ResultAt(0)
+}
+
+// ResultAt waits for the queries to finish and returns the documents of the
+// query at index i. The error of that query is returned if it failed.
+func (f *TypedFuture[T]) ResultAt(i int) ([]T, error) {
+	docs := []T{}
+	if err := f.ScanAt(i, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// One waits for the query to finish and returns its first document,
+// gocql.ErrNotFound is returned if there are none.
+func (f *TypedFuture[T]) One() (T, error) {
+	var doc T
+	err := f.ScanOne(&doc)
+
+	return doc, err
+}
+
+// ListAsync starts reading every document in the table in the background
+func (t *TypedTable[T]) ListAsync(ctx context.Context) *TypedFuture[T] {
+	return NewTypedFuture[T](t.Table.List().Async(ctx))
+}
+
+// ReadAsync starts reading the documents matching the relations in the
+// background
+func (t *TypedFilteredTable[T]) ReadAsync(ctx context.Context) *TypedFuture[T] {
+	return NewTypedFuture[T](t.FilteredTable.Read().Async(ctx))
+}
+
+// ReadAsync starts reading the document with the given id in the background,
+// use TypedFuture.One to wait for it
+func (t *TypedMapTable[K, T]) ReadAsync(ctx context.Context, id K) *TypedFuture[T] {
+	return NewTypedFuture[T](t.MapTable.Read(id).Async(ctx))
+}
@@ -0,0 +1,51 @@
+//go:build go1.18
+// +build go1.18
+
+package gocassa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedFuture(t *testing.T) {
+	ctx := context.Background()
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	events := NewTypedTable[memoryEvent](k, "events", []string{"userid"}, []string{"created"}, nil)
+	docs := NewTypedMapTable[string, memoryDocument](k, "docs", "id")
+
+	assert.Nil(t, events.Set(memoryEvent{"a", 1, "one"}).Execute())
+	assert.Nil(t, docs.Set(memoryDocument{ID: "a", Amount: 1}).Execute())
+
+	all := events.ListAsync(ctx)
+	filtered := events.Where(Eq("userid", "a")).ReadAsync(ctx)
+	one := docs.ReadAsync(ctx, "a")
+	missing := docs.ReadAsync(ctx, "x")
+
+	list, err := all.Result()
+	assert.Nil(t, err)
+	assert.Equal(t, []memoryEvent{{"a", 1, "one"}}, list)
+
+	list, err = filtered.Result()
+	assert.Nil(t, err)
+	assert.Equal(t, []memoryEvent{{"a", 1, "one"}}, list)
+
+	doc, err := one.One()
+	assert.Nil(t, err)
+	assert.Equal(t, memoryDocument{ID: "a", Amount: 1}, doc)
+
+	_, err = missing.One()
+	assert.Equal(t, gocql.ErrNotFound, err)
+
+	f := NewTypedFuture[memoryEvent](MultiQuery(
+		events.Where(Eq("userid", "a")).FilteredTable.Read(),
+		events.Where(Eq("userid", "b")).FilteredTable.Read(),
+	).Async(ctx))
+	assert.Nil(t, f.Wait())
+	list, err = f.ResultAt(1)
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+}
@@ -0,0 +1,50 @@
+package gocassa
+
+// CounterTable is a recipe for a table of counters keyed by a single
+// partition key, for example page views keyed by page. Every field of the
+// document other than the partition key must be a Counter.
+type CounterTable struct {
+	*Table
+
+	partitionKey string
+}
+
+func NewCounterTable(keyspace *Keyspace, name string, documentValue interface{}, partitionKey string) *CounterTable {
+	return &CounterTable{
+		Table:        NewTable(keyspace, name, documentValue, []string{partitionKey}, nil, &TableOptions{}),
+		partitionKey: partitionKey,
+	}
+}
+
+// Increment adds n to the counter field of the row with the given id, n may
+// be negative to decrement the counter. Counters which have not been written
+// start at zero.
+func (t *CounterTable) Increment(id interface{}, field string, n int) RunnableQuery {
+	return t.Where(Eq(t.partitionKey, id)).Update(map[string]interface{}{
+		field: CounterIncrement(n),
+	})
+}
+
+// IncrementFields adds the value of each field in m to the counters of the
+// row with the given id in a single update.
+func (t *CounterTable) IncrementFields(id interface{}, m map[string]int) RunnableQuery {
+	modifiers := make(map[string]interface{}, len(m))
+	for field, n := range m {
+		modifiers[field] = CounterIncrement(n)
+	}
+
+	return t.Where(Eq(t.partitionKey, id)).Update(modifiers)
+}
+
+func (t *CounterTable) Read(id interface{}) RunnableQuery {
+	return t.Where(Eq(t.partitionKey, id)).Read()
+}
+
+func (t *CounterTable) Delete(id interface{}) RunnableQuery {
+	return t.Where(Eq(t.partitionKey, id)).Delete()
+}
+
+func (t *CounterTable) WithOptions(options TableOptions) *CounterTable {
+	t.Table = t.Table.WithOptions(options)
+	return t
+}
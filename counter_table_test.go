@@ -0,0 +1,23 @@
+package gocassa
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterTable(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewCounterTable(k, "counters", CounterDocument{}, "id")
+
+	assert.Nil(t, tbl.Increment("a", "views", 2).Execute())
+	assert.Nil(t, tbl.Increment("a", "views", -1).Execute())
+	assert.Nil(t, tbl.IncrementFields("a", map[string]int{"views": 3, "clicks": 1}).Execute())
+
+	doc := CounterDocument{}
+	assert.Nil(t, tbl.Read("a").ScanOne(&doc))
+	assert.Equal(t, CounterDocument{ID: "a", Views: 4, Clicks: 1}, doc)
+
+	assert.Nil(t, tbl.Delete("a").Execute())
+	assert.NotNil(t, tbl.Read("a").ScanOne(&doc))
+}
@@ -0,0 +1,196 @@
+package gocassa
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/dancannon/gocassa/encoding"
+)
+
+// A decodePlan maps column names onto the fields of a struct type. Plans are
+// cached per type so that decoding a row only has to look up each column
+// rather than inspecting the struct and building a new decoder every time.
+type decodePlan struct {
+	fields map[string][]int
+}
+
+var decodePlanCache struct {
+	sync.RWMutex
+	m map[reflect.Type]*decodePlan
+}
+
+func cachedDecodePlan(t reflect.Type) *decodePlan {
+	decodePlanCache.RLock()
+	plan := decodePlanCache.m[t]
+	decodePlanCache.RUnlock()
+	if plan != nil {
+		return plan
+	}
+
+	fields := encoding.Fields(t)
+	plan = &decodePlan{
+		fields: make(map[string][]int, len(fields)),
+	}
+	for _, field := range fields {
+		plan.fields[strings.ToLower(field.Name)] = field.Index
+	}
+
+	decodePlanCache.Lock()
+	if decodePlanCache.m == nil {
+		decodePlanCache.m = map[reflect.Type]*decodePlan{}
+	}
+	decodePlanCache.m[t] = plan
+	decodePlanCache.Unlock()
+
+	return plan
+}
+
+// decodeRow copies the columns of a single row into dest, which can be a map,
+// a pointer to a map, a pointer to a struct or a pointer to a pointer to a
+// struct. Struct fields without a matching column are set to their zero
+// value. Any other destination is decoded using decodeResult.
+//
+// The row is not retained so callers may reuse it between calls.
+func decodeRow(row map[string]interface{}, dest interface{}) error {
+	switch d := dest.(type) {
+	case map[string]interface{}:
+		for k := range d {
+			delete(d, k)
+		}
+		for k, v := range row {
+			d[k] = v
+		}
+		return nil
+	case *map[string]interface{}:
+		m := make(map[string]interface{}, len(row))
+		for k, v := range row {
+			m[k] = v
+		}
+		*d = m
+		return nil
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("Cannot decode row into non-pointer %T", dest)
+	}
+	v = v.Elem()
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return decodeResult(row, dest)
+	}
+
+	plan := cachedDecodePlan(v.Type())
+	v.Set(reflect.Zero(v.Type()))
+	for column, value := range row {
+		index, ok := plan.fields[column]
+		if !ok {
+			if index, ok = plan.fields[strings.ToLower(column)]; !ok {
+				continue
+			}
+		}
+
+		field := encoding.FieldByIndex(v, index)
+		if err := decodeValue(value, field); err != nil {
+			return fmt.Errorf("Cannot decode column %s: %v", column, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeValue sets field to value, assigning it directly where the types allow
+// and falling back to decodeResult for anything which needs converting. Fields
+// implementing an unmarshaler always use it, as decodeResult does.
+func decodeValue(value interface{}, field reflect.Value) error {
+	if value == nil || !field.IsValid() {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	t := field.Type()
+	if encoding.IsUnmarshaler(t) {
+		u, ok, err := encoding.UnmarshalValue(value, t)
+		if err != nil {
+			return err
+		}
+		if ok {
+			field.Set(reflect.ValueOf(u))
+			return nil
+		}
+	}
+
+	switch {
+	case v.Type().AssignableTo(t):
+		field.Set(v)
+		return nil
+	case t.Kind() == reflect.Ptr && v.Type().AssignableTo(t.Elem()):
+		p := reflect.New(t.Elem())
+		p.Elem().Set(v)
+		field.Set(p)
+		return nil
+	case isNumberKind(v.Kind()) && isNumberKind(t.Kind()):
+		if !canConvertNumber(v, t) {
+			return fmt.Errorf("Cannot decode %v into %s without losing precision", value, t)
+		}
+		field.Set(v.Convert(t))
+		return nil
+	}
+
+	return decodeResult(value, field.Addr().Interface())
+}
+
+// canConvertNumber returns true if the number v can be converted to the
+// numeric type t without overflowing or dropping a fractional part
+func canConvertNumber(v reflect.Value, t reflect.Type) bool {
+	target := reflect.New(t).Elem()
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return !target.OverflowInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return n >= 0 && !target.OverflowUint(uint64(n))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := v.Uint()
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return n <= math.MaxInt64 && !target.OverflowInt(int64(n))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return !target.OverflowUint(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && !target.OverflowInt(int64(f))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return f == math.Trunc(f) && f >= 0 && f < math.MaxUint64 && !target.OverflowUint(uint64(f))
+		case reflect.Float32, reflect.Float64:
+			return math.IsNaN(f) || math.IsInf(f, 0) || !target.OverflowFloat(f)
+		}
+	}
+
+	return true
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
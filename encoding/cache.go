@@ -16,6 +16,8 @@ type field struct {
 	typ       reflect.Type
 	omitEmpty bool
 	quoted    bool
+	sensitive bool
+	cqlType   string
 }
 
 func fillField(f field) field {
@@ -129,6 +131,8 @@ func typeFields(t reflect.Type) []field {
 						index:     index,
 						typ:       ft,
 						omitEmpty: opts.Contains("omitempty"),
+						sensitive: opts.Contains("sensitive"),
+						cqlType:   cqlTypeOption(opts),
 					}))
 					if count[f.typ] > 1 {
 						// If there were multiple instances, add a second,
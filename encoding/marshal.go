@@ -0,0 +1,240 @@
+package encoding
+
+import (
+	stdencoding "encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
+)
+
+var (
+	gocqlMarshalerType   = reflect.TypeOf((*gocql.Marshaler)(nil)).Elem()
+	gocqlUnmarshalerType = reflect.TypeOf((*gocql.Unmarshaler)(nil)).Elem()
+	textMarshalerType    = reflect.TypeOf((*stdencoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType  = reflect.TypeOf((*stdencoding.TextUnmarshaler)(nil)).Elem()
+	jsonMarshalerType    = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType  = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// nativeTypes are marshalled by gocql itself even though they implement
+// encoding.TextMarshaler or json.Marshaler
+var nativeTypes = map[reflect.Type]bool{
+	reflect.TypeOf(time.Time{}):  true,
+	reflect.TypeOf(gocql.UUID{}): true,
+	reflect.TypeOf(big.Int{}):    true,
+	reflect.TypeOf(net.IP{}):     true,
+}
+
+func isNativeType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// inf.Dec is used for decimal columns
+	return nativeTypes[t] || t.PkgPath() == "gopkg.in/inf.v0"
+}
+
+// marshalError is bound in place of a value which could not be marshalled so
+// that the error is returned when the query is executed
+type marshalError struct {
+	err error
+}
+
+func (e marshalError) MarshalCQL(info gocql.TypeInfo) ([]byte, error) {
+	return nil, e.err
+}
+
+// MarshalValue converts v into the value bound to a query. Types implementing
+// gocql.Marshaler are left for gocql to marshal, while types implementing
+// encoding.TextMarshaler or json.Marshaler are converted to a string so that
+// they are stored as text. Types which gocql marshals natively, such as
+// time.Time and gocql.UUID, and any other value are returned unchanged.
+//
+// If marshalling fails the returned value reports the error when the query
+// is executed.
+func MarshalValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	t := rv.Type()
+	if isNativeType(t) || t.Implements(gocqlMarshalerType) {
+		return v
+	}
+
+	// Use the pointer if only it implements the marshaler
+	if t.Kind() != reflect.Ptr && !t.Implements(textMarshalerType) && !t.Implements(jsonMarshalerType) {
+		pt := reflect.PtrTo(t)
+		if pt.Implements(gocqlMarshalerType) {
+			return v
+		}
+		if !pt.Implements(textMarshalerType) && !pt.Implements(jsonMarshalerType) {
+			return v
+		}
+		p := reflect.New(t)
+		p.Elem().Set(rv)
+		rv = p
+		v = p.Interface()
+	}
+
+	var data []byte
+	var err error
+	switch m := v.(type) {
+	case stdencoding.TextMarshaler:
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return (*string)(nil)
+		}
+		data, err = m.MarshalText()
+	case json.Marshaler:
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return (*string)(nil)
+		}
+		data, err = m.MarshalJSON()
+	default:
+		return v
+	}
+	if err != nil {
+		return marshalError{err: fmt.Errorf("Cannot marshal %T: %v", v, err)}
+	}
+
+	return string(data)
+}
+
+// IsTextMarshaler returns true if values of type t are stored as text by
+// MarshalValue
+func IsTextMarshaler(t reflect.Type) bool {
+	if isNativeType(t) {
+		return false
+	}
+	pt := t
+	if t.Kind() != reflect.Ptr {
+		pt = reflect.PtrTo(t)
+	}
+	if t.Implements(gocqlMarshalerType) || pt.Implements(gocqlMarshalerType) {
+		return false
+	}
+
+	return pt.Implements(textMarshalerType) || pt.Implements(jsonMarshalerType)
+}
+
+// IsUnmarshaler returns true if values of type t are decoded by
+// UnmarshalValue
+func IsUnmarshaler(t reflect.Type) bool {
+	if isNativeType(t) {
+		return false
+	}
+	pt := t
+	if t.Kind() != reflect.Ptr {
+		pt = reflect.PtrTo(t)
+	}
+
+	return pt.Implements(gocqlUnmarshalerType) || pt.Implements(textUnmarshalerType) || pt.Implements(jsonUnmarshalerType)
+}
+
+// UnmarshalValue converts the value read from a column into a value of type t
+// using the gocql.Unmarshaler, encoding.TextUnmarshaler or json.Unmarshaler
+// implementation of t. ok is false if t implements none of them, or the value
+// does not need converting.
+func UnmarshalValue(value interface{}, t reflect.Type) (result interface{}, ok bool, err error) {
+	if value == nil || isNativeType(t) || reflect.TypeOf(value) == t {
+		return nil, false, nil
+	}
+
+	elem := t
+	if t.Kind() == reflect.Ptr {
+		elem = t.Elem()
+	}
+	p := reflect.New(elem)
+
+	var text []byte
+	switch value := value.(type) {
+	case string:
+		text = []byte(value)
+	case []byte:
+		text = value
+	}
+
+	switch u := p.Interface().(type) {
+	case gocql.Unmarshaler:
+		info, err := nativeTypeInfo(value)
+		if err != nil {
+			return nil, false, err
+		}
+		data, err := gocql.Marshal(info, value)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := u.UnmarshalCQL(info, data); err != nil {
+			return nil, false, err
+		}
+	case stdencoding.TextUnmarshaler:
+		if text == nil {
+			return nil, false, nil
+		}
+		if err := u.UnmarshalText(text); err != nil {
+			return nil, false, err
+		}
+	case json.Unmarshaler:
+		if text == nil {
+			return nil, false, nil
+		}
+		if err := u.UnmarshalJSON(text); err != nil {
+			return nil, false, err
+		}
+	default:
+		return nil, false, nil
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return p.Interface(), true, nil
+	}
+	return p.Elem().Interface(), true, nil
+}
+
+// nativeTypeInfo returns the type of the column gocql reads as v
+func nativeTypeInfo(v interface{}) (gocql.TypeInfo, error) {
+	var typ gocql.Type
+	switch v.(type) {
+	case string:
+		typ = gocql.TypeVarchar
+	case []byte:
+		typ = gocql.TypeBlob
+	case bool:
+		typ = gocql.TypeBoolean
+	case int8:
+		typ = gocql.TypeTinyInt
+	case int16:
+		typ = gocql.TypeSmallInt
+	case int, int32:
+		typ = gocql.TypeInt
+	case int64:
+		typ = gocql.TypeBigInt
+	case float32:
+		typ = gocql.TypeFloat
+	case float64:
+		typ = gocql.TypeDouble
+	case time.Time:
+		typ = gocql.TypeTimestamp
+	case gocql.UUID:
+		typ = gocql.TypeUUID
+	case *big.Int:
+		typ = gocql.TypeVarint
+	case *inf.Dec:
+		typ = gocql.TypeDecimal
+	case net.IP:
+		typ = gocql.TypeInet
+	case gocql.Duration:
+		typ = gocql.TypeDuration
+	default:
+		return nil, fmt.Errorf("Cannot unmarshal value of type %T", v)
+	}
+
+	return gocql.NewNativeType(4, typ, ""), nil
+}
@@ -0,0 +1,144 @@
+package encoding
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	if l < 0 {
+		return nil, errors.New("negative level")
+	}
+	return []byte(strings.Repeat("*", int(l))), nil
+}
+
+func (l *level) UnmarshalText(b []byte) error {
+	*l = level(len(b))
+	return nil
+}
+
+type point struct {
+	X, Y int
+}
+
+func (p *point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{p.X, p.Y})
+}
+
+func (p *point) UnmarshalJSON(b []byte) error {
+	var xy []int
+	if err := json.Unmarshal(b, &xy); err != nil {
+		return err
+	}
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
+
+type cents int64
+
+func (c cents) MarshalCQL(info gocql.TypeInfo) ([]byte, error) {
+	return gocql.Marshal(info, int64(c))
+}
+
+func (c *cents) UnmarshalCQL(info gocql.TypeInfo, data []byte) error {
+	var v int64
+	if err := gocql.Unmarshal(info, data, &v); err != nil {
+		return err
+	}
+	*c = cents(v)
+	return nil
+}
+
+func TestMarshalValue(t *testing.T) {
+	now := time.Now()
+	var nilPoint *point
+	var tests = []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{level(3), "***"},
+		{point{1, 2}, "[1,2]"},
+		{&point{3, 4}, "[3,4]"},
+		{nilPoint, (*string)(nil)},
+		{cents(5), cents(5)},
+		{now, now},
+		{"text", "text"},
+		{nil, nil},
+	}
+	for _, test := range tests {
+		if v := MarshalValue(test.value); !reflect.DeepEqual(test.expected, v) {
+			t.Errorf("expected %#v to marshal to %#v but got %#v", test.value, test.expected, v)
+		}
+	}
+
+	v := MarshalValue(level(-1))
+	m, ok := v.(gocql.Marshaler)
+	if !ok {
+		t.Fatalf("expected a marshal error but got %#v", v)
+	}
+	if _, err := m.MarshalCQL(nil); err == nil {
+		t.Error("expected the marshal error to be returned")
+	}
+}
+
+func TestUnmarshalValue(t *testing.T) {
+	var tests = []struct {
+		value    interface{}
+		typ      reflect.Type
+		expected interface{}
+	}{
+		{"**", reflect.TypeOf(level(0)), level(2)},
+		{"[1,2]", reflect.TypeOf(point{}), point{1, 2}},
+		{"[1,2]", reflect.TypeOf(&point{}), &point{1, 2}},
+		{int64(7), reflect.TypeOf(cents(0)), cents(7)},
+	}
+	for _, test := range tests {
+		v, ok, err := UnmarshalValue(test.value, test.typ)
+		if err != nil || !ok {
+			t.Errorf("expected %#v to unmarshal but got %v, %v", test.value, ok, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.expected, v) {
+			t.Errorf("expected %#v but got %#v", test.expected, v)
+		}
+	}
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(level(0)), reflect.TypeOf(&point{}), reflect.TypeOf(cents(0))} {
+		if !IsUnmarshaler(typ) {
+			t.Errorf("expected %s to be an unmarshaler", typ)
+		}
+	}
+	if IsUnmarshaler(reflect.TypeOf(time.Time{})) || IsUnmarshaler(reflect.TypeOf(0)) {
+		t.Error("expected native types not to be unmarshalers")
+	}
+
+	if _, ok, _ := UnmarshalValue("text", reflect.TypeOf("")); ok {
+		t.Error("expected strings to be left alone")
+	}
+	if _, ok, _ := UnmarshalValue("2020-01-01T00:00:00Z", reflect.TypeOf(time.Time{})); ok {
+		t.Error("expected native types to be left alone")
+	}
+}
+
+func TestFieldsCQLType(t *testing.T) {
+	type Document struct {
+		Amount cents          `cql:"amount,type=bigint"`
+		Tags   map[string]int `cql:",sensitive,type=map<text, int>"`
+		Name   string
+	}
+
+	fields := Fields(reflect.TypeOf(Document{}))
+	types := []string{fields[0].CQLType, fields[1].CQLType, fields[2].CQLType}
+	assertFieldsEqual(t, []string{"bigint", "map<text, int>", ""}, types)
+	if !fields[1].Sensitive {
+		t.Error("expected the sensitive option to be parsed alongside the type")
+	}
+}
@@ -15,6 +15,9 @@ import "reflect"
 //
 //   // Field appears in the resulting map as key "myName"
 //   Field int "myName"
+//
+// Values are converted using MarshalValue, so fields implementing
+// encoding.TextMarshaler or json.Marshaler appear as strings.
 func StructToMap(val interface{}) map[string]interface{} {
 	// indirect so function works with both structs and pointers to them
 	structVal := reflect.Indirect(reflect.ValueOf(val))
@@ -26,7 +29,7 @@ func StructToMap(val interface{}) map[string]interface{} {
 	mapVal := make(map[string]interface{}, len(structFields))
 	for _, info := range structFields {
 		field := fieldByIndex(structVal, info.index)
-		mapVal[info.name] = field.Interface()
+		mapVal[info.name] = MarshalValue(field.Interface())
 	}
 	return mapVal
 }
@@ -75,6 +78,49 @@ func FieldsAndValues(val interface{}) ([]string, []interface{}, bool) {
 	return fields, values, true
 }
 
+// A Field describes a struct field which is mapped to a column.
+type Field struct {
+	// Name is the column name, as described in StructToMap
+	Name string
+	// Index is the index sequence of the field for reflect.Value.FieldByIndex,
+	// it contains more than one element for fields of embedded structs
+	Index []int
+	Type  reflect.Type
+	// Sensitive is set by the "sensitive" tag option, for example
+	// `cql:"email,sensitive"`, and marks columns whose values must not be
+	// logged
+	Sensitive bool
+	// CQLType is set by the "type" tag option, for example
+	// `cql:"status,type=text"`, and overrides the column type which is
+	// otherwise derived from the field type
+	CQLType string
+}
+
+// Fields returns the fields of the given struct type which are mapped to
+// columns, in the order they are declared. For details on how the field names
+// are determined please see StructToMap.
+func Fields(t reflect.Type) []Field {
+	structFields := cachedTypeFields(t)
+	fields := make([]Field, len(structFields))
+	for i, info := range structFields {
+		fields[i] = Field{
+			Name:      info.name,
+			Index:     info.index,
+			Type:      info.typ,
+			Sensitive: info.sensitive,
+			CQLType:   info.cqlType,
+		}
+	}
+	return fields
+}
+
+// FieldByIndex returns the nested field of the struct v corresponding to index,
+// allocating any nil embedded struct pointers along the way. It returns the
+// zero Value if a nil embedded pointer cannot be allocated.
+func FieldByIndex(v reflect.Value, index []int) reflect.Value {
+	return fieldByIndex(v, index)
+}
+
 func fieldByIndex(v reflect.Value, index []int) reflect.Value {
 	for _, i := range index {
 		if v.Kind() == reflect.Ptr {
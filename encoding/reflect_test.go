@@ -1,6 +1,8 @@
 package encoding
 
 import (
+	"reflect"
+
 	"github.com/gocql/gocql"
 
 	"testing"
@@ -182,3 +184,26 @@ func assertValuesEqual(t *testing.T, a, b []interface{}) {
 		}
 	}
 }
+
+func TestFields(t *testing.T) {
+	type Embedded struct {
+		Text string
+	}
+	type Document struct {
+		*Embedded
+		ID      string `cql:"id"`
+		Ignored string `cql:"-"`
+	}
+
+	fields := Fields(reflect.TypeOf(Document{}))
+	if len(fields) != 2 {
+		t.Fatalf("expected 2 fields but got %v", fields)
+	}
+	assertFieldsEqual(t, []string{"Text", "id"}, []string{fields[0].Name, fields[1].Name})
+
+	doc := Document{}
+	FieldByIndex(reflect.ValueOf(&doc).Elem(), fields[0].Index).SetString("hello gocassa")
+	if doc.Embedded == nil || doc.Text != "hello gocassa" {
+		t.Errorf("expected embedded field to be set but got %v", doc.Embedded)
+	}
+}
@@ -25,6 +25,13 @@ func parseTag(tag string) (string, tagOptions) {
 	return tag, tagOptions("")
 }
 
+// cqlTypeOption returns the column type set by the type option, for example
+// `cql:"status,type=text"`
+func cqlTypeOption(o tagOptions) string {
+	typ, _ := o.Value("type")
+	return typ
+}
+
 func isValidTag(s string) bool {
 	if s == "" {
 		return false
@@ -65,3 +72,35 @@ func (o tagOptions) Contains(optionName string) bool {
 	}
 	return false
 }
+
+// Value returns the value of an option of the form name=value, for example
+// the type option of `cql:"amount,type=decimal"`. Commas inside angle brackets
+// are part of the value so that collection types such as map<text, int> can
+// be used.
+func (o tagOptions) Value(optionName string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		depth, i := 0, 0
+		for ; i < len(s); i++ {
+			switch s[i] {
+			case '<':
+				depth++
+			case '>':
+				depth--
+			}
+			if s[i] == ',' && depth <= 0 {
+				break
+			}
+		}
+
+		option := s[:i]
+		if strings.HasPrefix(option, optionName+"=") {
+			return strings.TrimSpace(option[len(optionName)+1:]), true
+		}
+		if i >= len(s) {
+			break
+		}
+		s = s[i+1:]
+	}
+	return "", false
+}
@@ -19,3 +19,73 @@ func (r RowNotFoundError) Error() string {
 	}
 	return fmt.Sprintf("%v:%v: No rows returned", f, r.line)
 }
+
+// MigrationLockError is returned by a Migrator if the migration lock is held
+// by another instance.
+type MigrationLockError struct {
+	Owner string
+}
+
+func (e MigrationLockError) Error() string {
+	return fmt.Sprintf("Migrations are locked by %v", e.Owner)
+}
+
+// QueryError is the error of a single query executed by
+// RunnableQueries.ExecuteAll or RunnableQueries.ExecuteParallel.
+type QueryError struct {
+	// Index is the index of the failed query in RunnableQueries.Queries
+	Index int
+	Err   error
+}
+
+func (e QueryError) Error() string {
+	return fmt.Sprintf("Query %d failed: %v", e.Index, e.Err)
+}
+
+func (e QueryError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is returned by RunnableQueries.ExecuteAll and
+// RunnableQueries.ExecuteParallel if any of the queries failed. The errors are
+// sorted by the index of the query.
+type MultiError struct {
+	Errors []QueryError
+}
+
+func (e MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d queries failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// BatchError is the error of a single batch executed by
+// RunnableQueries.ExecuteBatches.
+type BatchError struct {
+	Batch Batch
+	Err   error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("Batch of queries %v failed: %v", e.Batch.Indexes, e.Err)
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// MultiBatchError is returned by RunnableQueries.ExecuteBatches if any of the
+// batches failed. Queries in the other batches have been applied.
+type MultiBatchError struct {
+	Errors []BatchError
+}
+
+func (e MultiBatchError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d batches failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
@@ -1,5 +1,7 @@
 package gocassa
 
+import "context"
+
 // A QueryExecutor implements the functions required to execute queries against
 // a Cassandra cluster
 type QueryExecutor interface {
@@ -7,6 +9,10 @@ type QueryExecutor interface {
 	// and discards the rest
 	QueryOne(query QueryGenerator) (map[string]interface{}, error)
 
+	// QueryOneContext is the same as QueryOne except that the query is bound to
+	// the given context, which can be used to cancel it or set a deadline.
+	QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error)
+
 	// QueryCAS executes a lightweight transaction (i.e. an UPDATE or INSERT
 	// statement containing an IF clause). If the transaction fails because
 	// the existing values did not match, the previous values will be returned
@@ -15,17 +21,33 @@ type QueryExecutor interface {
 	// Query executes the query, returns a slice of maps containing each row.
 	Query(query QueryGenerator) ([]map[string]interface{}, error)
 
+	// QueryContext is the same as Query except that the query is bound to the
+	// given context.
+	QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error)
+
 	// Iter executes the query and returns an iterator capable of iterating over
 	// all results.
 	Iter(query QueryGenerator) Iter
 
+	// IterContext is the same as Iter except that the query, and any
+	// subsequent page fetches, are bound to the given context.
+	IterContext(ctx context.Context, query QueryGenerator) Iter
+
 	// Execute executes a query and discards any results
 	Execute(query QueryGenerator) error
 
+	// ExecuteContext is the same as Execute except that the query is bound to
+	// the given context.
+	ExecuteContext(ctx context.Context, query QueryGenerator) error
+
 	// ExecuteBatch executes a batch operation and returns nil if successful
 	// otherwise an error is returned describing the failure.
 	ExecuteBatch(queries []QueryGenerator, options QueryOptions) error
 
+	// ExecuteBatchContext is the same as ExecuteBatch except that the batch is
+	// bound to the given context.
+	ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error
+
 	// ExecuteBatchCAS  executes a batch operation and returns true if successful,
 	// the initial result as a map and an iterator (to scan aditional rows if
 	// more than one conditional statement) was sent.
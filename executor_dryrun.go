@@ -0,0 +1,421 @@
+package gocassa
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
+)
+
+// DryRunExecutor is a query executor which does not execute any queries,
+// instead it renders each one to a CQL script which can be reviewed before it
+// is run, for example using cqlsh. Values are inlined as CQL literals, except
+// for the values of sensitive columns which are redacted, and the consistency
+// of each query is set with CONSISTENCY and SERIAL CONSISTENCY commands
+// whenever it changes.
+//
+// Reads return no rows unless Results is set.
+type DryRunExecutor struct {
+	// Consistency is the consistency level of queries which do not set one,
+	// it defaults to QUORUM which is the default of gocql.
+	Consistency gocql.Consistency
+	// Results returns the rows for a read, if nil reads return no rows
+	Results func(query QueryGenerator) []map[string]interface{}
+
+	mtx               sync.Mutex
+	statements        []string
+	consistency       string
+	serialConsistency string
+}
+
+// NewDryRunExecutor creates a dry run executor with an empty script
+func NewDryRunExecutor() *DryRunExecutor {
+	return &DryRunExecutor{
+		Consistency: gocql.Quorum,
+	}
+}
+
+// Statements returns the statements rendered so far, in the order they were
+// executed. CONSISTENCY commands are included as separate statements.
+func (qe *DryRunExecutor) Statements() []string {
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	return append([]string{}, qe.statements...)
+}
+
+// Script returns the statements rendered so far as a CQL script
+func (qe *DryRunExecutor) Script() string {
+	buf := &bytes.Buffer{}
+	qe.WriteTo(buf)
+
+	return buf.String()
+}
+
+// WriteTo writes the statements rendered so far to w as a CQL script
+func (qe *DryRunExecutor) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, stmt := range qe.Statements() {
+		n, err := io.WriteString(w, stmt+";\n")
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// Reset removes all of the rendered statements
+func (qe *DryRunExecutor) Reset() {
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	qe.statements = nil
+	qe.consistency = ""
+	qe.serialConsistency = ""
+}
+
+// render adds the query to the script
+func (qe *DryRunExecutor) render(query QueryGenerator) error {
+	cql, err := renderStatement(query)
+	if err != nil {
+		return err
+	}
+
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	options := query.Options()
+	qe.setConsistency(options.Consistency, options.SerialConsistency)
+	qe.statements = append(qe.statements, cql)
+
+	return nil
+}
+
+// renderBatch adds the batch to the script as a single BEGIN BATCH statement.
+// Cassandra does not allow a TTL on the batch itself so the TTL of the batch
+// is rendered on each statement which does not set its own.
+func (qe *DryRunExecutor) renderBatch(queries []QueryGenerator, options QueryOptions) error {
+	if len(queries) == 0 {
+		return fmt.Errorf("No queries in batch")
+	}
+
+	buf := &bytes.Buffer{}
+	switch options.BatchType {
+	case gocql.UnloggedBatch:
+		buf.WriteString("BEGIN UNLOGGED BATCH")
+	case gocql.CounterBatch:
+		buf.WriteString("BEGIN COUNTER BATCH")
+	default:
+		buf.WriteString("BEGIN BATCH")
+	}
+	if !options.Timestamp.IsZero() {
+		buf.WriteString(" USING TIMESTAMP ")
+		buf.WriteString(strconv.FormatInt(options.Timestamp.UnixNano()/1000, 10))
+	}
+	buf.WriteString("\n")
+
+	for _, query := range queries {
+		if queryOptions := query.Options(); options.TTL > 0 && queryOptions.TTL == 0 {
+			queryOptions.TTL = options.TTL
+			query = query.WithOptions(queryOptions)
+		}
+
+		cql, err := renderStatement(query)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString("  ")
+		buf.WriteString(cql)
+		buf.WriteString(";\n")
+	}
+	buf.WriteString("APPLY BATCH")
+
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	qe.setConsistency(options.Consistency, options.SerialConsistency)
+	qe.statements = append(qe.statements, buf.String())
+
+	return nil
+}
+
+// setConsistency adds CONSISTENCY and SERIAL CONSISTENCY commands to the
+// script if the consistency differs from the previous statement
+func (qe *DryRunExecutor) setConsistency(consistency *gocql.Consistency, serial *gocql.SerialConsistency) {
+	c := qe.Consistency
+	if consistency != nil {
+		c = *consistency
+	}
+
+	if c.String() != qe.consistency {
+		qe.consistency = c.String()
+		qe.statements = append(qe.statements, "CONSISTENCY "+qe.consistency)
+	}
+
+	// An empty serial consistency is the default of cqlsh, which is SERIAL
+	s := gocql.Serial
+	if serial != nil {
+		s = *serial
+	}
+	current := qe.serialConsistency
+	if current == "" {
+		current = gocql.Serial.String()
+	}
+	if s.String() != current {
+		qe.serialConsistency = s.String()
+		qe.statements = append(qe.statements, "SERIAL CONSISTENCY "+qe.serialConsistency)
+	}
+}
+
+func (qe *DryRunExecutor) results(query QueryGenerator) []map[string]interface{} {
+	if qe.Results == nil {
+		return []map[string]interface{}{}
+	}
+
+	return qe.Results(query)
+}
+
+func (qe *DryRunExecutor) QueryOne(query QueryGenerator) (map[string]interface{}, error) {
+	return qe.QueryOneContext(context.Background(), query)
+}
+
+func (qe *DryRunExecutor) QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error) {
+	if err := qe.render(query); err != nil {
+		return nil, err
+	}
+
+	rows := qe.results(query)
+	if len(rows) == 0 {
+		return nil, gocql.ErrNotFound
+	}
+
+	return rows[0], nil
+}
+
+func (qe *DryRunExecutor) QueryCAS(query QueryGenerator) (result map[string]interface{}, applied bool, err error) {
+	if err := qe.render(query); err != nil {
+		return nil, false, err
+	}
+
+	return map[string]interface{}{}, true, nil
+}
+
+func (qe *DryRunExecutor) Query(query QueryGenerator) ([]map[string]interface{}, error) {
+	return qe.QueryContext(context.Background(), query)
+}
+
+func (qe *DryRunExecutor) QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error) {
+	if err := qe.render(query); err != nil {
+		return nil, err
+	}
+
+	return qe.results(query), nil
+}
+
+func (qe *DryRunExecutor) Iter(query QueryGenerator) Iter {
+	return qe.IterContext(context.Background(), query)
+}
+
+func (qe *DryRunExecutor) IterContext(ctx context.Context, query QueryGenerator) Iter {
+	if err := qe.render(query); err != nil {
+		return &rowsIter{err: err}
+	}
+
+	rows := qe.results(query)
+	return &rowsIter{rows: rows, numRows: len(rows)}
+}
+
+func (qe *DryRunExecutor) Execute(query QueryGenerator) error {
+	return qe.ExecuteContext(context.Background(), query)
+}
+
+func (qe *DryRunExecutor) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	return qe.render(query)
+}
+
+func (qe *DryRunExecutor) ExecuteBatch(queries []QueryGenerator, options QueryOptions) error {
+	return qe.ExecuteBatchContext(context.Background(), queries, options)
+}
+
+func (qe *DryRunExecutor) ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error {
+	return qe.renderBatch(queries, options)
+}
+
+func (qe *DryRunExecutor) ExecuteBatchCAS(queries []QueryGenerator, options QueryOptions) (result map[string]interface{}, iter Iter, applied bool, err error) {
+	if err := qe.renderBatch(queries, options); err != nil {
+		return nil, nil, false, err
+	}
+
+	return map[string]interface{}{}, &rowsIter{}, true, nil
+}
+
+func (qe *DryRunExecutor) Close() {}
+
+// renderStatement renders the statement of the query with its values inlined,
+// the values of sensitive columns are redacted where the query supports it
+func renderStatement(query QueryGenerator) (string, error) {
+	var stmt string
+	var values []interface{}
+	if q, ok := query.(redactedStatementGenerator); ok {
+		stmt, values = q.generateRedactedStatement()
+	} else {
+		stmt, values = query.GenerateStatement()
+	}
+
+	return inlineValues(stmt, values)
+}
+
+// inlineValues replaces each bind marker in the statement with the matching
+// value rendered as a CQL literal. Question marks inside string literals are
+// left as they are.
+func inlineValues(stmt string, values []interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	quoted := false
+	n := 0
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			if n >= len(values) {
+				return "", fmt.Errorf("Not enough values for statement %q", stmt)
+			}
+
+			// IN relations take a tuple of values rather than a list
+			before := strings.ToUpper(strings.TrimSpace(stmt[:i]))
+			tuple := strings.HasSuffix(before, " IN")
+
+			literal, err := cqlLiteral(values[n], tuple)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(literal)
+			n++
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	if n != len(values) {
+		return "", fmt.Errorf("Too many values for statement %q", stmt)
+	}
+
+	return buf.String(), nil
+}
+
+// cqlLiteral renders v as a CQL literal, slices are rendered as a tuple if
+// tuple is true and as a list otherwise
+func cqlLiteral(v interface{}, tuple bool) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'", nil
+	case []byte:
+		if v == nil {
+			return "NULL", nil
+		}
+		return "0x" + hex.EncodeToString(v), nil
+	case time.Time:
+		return "'" + v.UTC().Format("2006-01-02 15:04:05.000-0700") + "'", nil
+	case gocql.UUID:
+		return v.String(), nil
+	case *big.Int:
+		if v == nil {
+			return "NULL", nil
+		}
+		return v.String(), nil
+	case *inf.Dec:
+		if v == nil {
+			return "NULL", nil
+		}
+		return v.String(), nil
+	case net.IP:
+		if v == nil {
+			return "NULL", nil
+		}
+		return "'" + v.String() + "'", nil
+	case Modifier:
+		return "", fmt.Errorf("Cannot bind a modifier as a value")
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return cqlLiteral(rv.String(), tuple)
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsNaN(f):
+			return "NaN", nil
+		case math.IsInf(f, 1):
+			return "Infinity", nil
+		case math.IsInf(f, -1):
+			return "-Infinity", nil
+		}
+		return strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()), nil
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return cqlLiteral(rv.Elem().Interface(), tuple)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() && !tuple {
+			return "NULL", nil
+		}
+		elems := make([]string, rv.Len())
+		for i := range elems {
+			elem, err := cqlLiteral(rv.Index(i).Interface(), false)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = elem
+		}
+		if tuple {
+			return "(" + strings.Join(elems, ",") + ")", nil
+		}
+		return "[" + strings.Join(elems, ",") + "]", nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		pairs := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			key, err := cqlLiteral(k.Interface(), false)
+			if err != nil {
+				return "", err
+			}
+			value, err := cqlLiteral(rv.MapIndex(k).Interface(), false)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+":"+value)
+		}
+		sort.Strings(pairs)
+		return "{" + strings.Join(pairs, ",") + "}", nil
+	}
+
+	return "", fmt.Errorf("Cannot render value of type %T as CQL", v)
+}
@@ -0,0 +1,138 @@
+package gocassa
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/inf.v0"
+)
+
+func TestDryRunExecutor(t *testing.T) {
+	qe := NewDryRunExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+	one := gocql.One
+
+	assert.Nil(t, tbl.Set(Document{"a", "it's", "c?", "d"}).WithOptions(QueryOptions{
+		TTL: time.Hour,
+	}).Execute())
+	assert.Nil(t, tbl.Where(In("fielda", "a", "b")).Delete().WithOptions(QueryOptions{
+		Consistency: &one,
+	}).Execute())
+	assert.Nil(t, tbl.Where(Eq("fielda", "a")).Update(map[string]interface{}{
+		"fieldb": "b?",
+	}).Execute())
+	assert.Nil(t, MultiQuery(
+		tbl.Set(Document{"e", "f", "g", "h"}),
+		tbl.Where(Eq("fielda", "e")).Delete(),
+	).WithOptions(QueryOptions{BatchType: gocql.UnloggedBatch}).ExecuteBatch())
+
+	assert.Equal(t, `CONSISTENCY QUORUM;
+UPDATE test.test SET fieldb = 'it''s',fieldc = 'c?',fieldd = 'd' WHERE fielda = 'a' USING TTL 3600;
+CONSISTENCY ONE;
+DELETE FROM test.test WHERE fielda IN ('a','b');
+CONSISTENCY QUORUM;
+UPDATE test.test SET fieldb = 'b?' WHERE fielda = 'a';
+BEGIN UNLOGGED BATCH
+  UPDATE test.test SET fieldb = 'f',fieldc = 'g',fieldd = 'h' WHERE fielda = 'e';
+  DELETE FROM test.test WHERE fielda = 'e';
+APPLY BATCH;
+`, qe.Script())
+
+	buf := &bytes.Buffer{}
+	n, err := qe.WriteTo(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, qe.Script(), buf.String())
+}
+
+func TestDryRunExecutor_batchOptions(t *testing.T) {
+	qe := NewDryRunExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", SensitiveDocument{}, []string{"id"}, nil, nil)
+	localSerial := gocql.LocalSerial
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, MultiQuery(
+			tbl.Set(SensitiveDocument{ID: "a", Email: "a@example.com", Nickname: "a"}),
+			tbl.Set(SensitiveDocument{ID: "b", Nickname: "b"}).WithOptions(QueryOptions{TTL: time.Minute}),
+		).WithOptions(QueryOptions{
+			TTL:               time.Hour,
+			SerialConsistency: &localSerial,
+		}).ExecuteBatch())
+	}
+	assert.Nil(t, tbl.Where(Eq("id", "a")).Delete().Execute())
+
+	batch := `BEGIN BATCH
+  UPDATE test.test SET email = '<redacted>',nickname = 'a',tags = '<redacted>' WHERE id = 'a' USING TTL 3600;
+  UPDATE test.test SET email = '<redacted>',nickname = 'b',tags = '<redacted>' WHERE id = 'b' USING TTL 60;
+APPLY BATCH;
+`
+	assert.Equal(t, "CONSISTENCY QUORUM;\nSERIAL CONSISTENCY LOCAL_SERIAL;\n"+batch+batch+
+		"SERIAL CONSISTENCY SERIAL;\nDELETE FROM test.test WHERE id = 'a';\n", qe.Script())
+}
+
+func TestDryRunExecutor_results(t *testing.T) {
+	qe := NewDryRunExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	docs := []Document{}
+	assert.Nil(t, tbl.List().Scan(&docs))
+	assert.Empty(t, docs)
+
+	qe.Results = func(query QueryGenerator) []map[string]interface{} {
+		return []map[string]interface{}{{"fielda": "a"}}
+	}
+	assert.Nil(t, tbl.List().Scan(&docs))
+	assert.Equal(t, []Document{{FieldA: "a"}}, docs)
+
+	assert.Equal(t, []string{
+		"CONSISTENCY QUORUM",
+		"SELECT * FROM test.test",
+		"SELECT * FROM test.test",
+	}, qe.Statements())
+
+	qe.Reset()
+	assert.Empty(t, qe.Script())
+}
+
+func TestCQLLiteral(t *testing.T) {
+	id := gocql.MustRandomUUID()
+	for _, tt := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "NULL"},
+		{"a'b", "'a''b'"},
+		{true, "true"},
+		{int64(-5), "-5"},
+		{uint8(5), "5"},
+		{1.5, "1.5"},
+		{float32(0.1), "0.1"},
+		{[]byte{0xca, 0xfe}, "0xcafe"},
+		{time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC), "'2020-01-02 03:04:05.006+0000'"},
+		{id, id.String()},
+		{[]string{"a", "b"}, "['a','b']"},
+		{[]string(nil), "NULL"},
+		{map[string]int{"b": 2, "a": 1}, "{'a':1,'b':2}"},
+		{big.NewInt(-12), "-12"},
+		{(*big.Int)(nil), "NULL"},
+		{inf.NewDec(12345, 2), "123.45"},
+		{net.ParseIP("10.0.0.1"), "'10.0.0.1'"},
+		{net.ParseIP("2001:db8::1"), "'2001:db8::1'"},
+		{net.IP(nil), "NULL"},
+	} {
+		literal, err := cqlLiteral(tt.value, false)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.expected, literal)
+	}
+
+	_, err := cqlLiteral(struct{}{}, false)
+	assert.NotNil(t, err)
+}
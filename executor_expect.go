@@ -0,0 +1,571 @@
+package gocassa
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gocql/gocql"
+)
+
+// TestingT is the subset of testing.T used to report unmet expectations
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// ExpectExecutor is a query executor for tests which matches queries against
+// expectations built from the parts of the query, such as the table, query
+// type, relations and values, rather than the generated CQL. This means tests
+// do not break when the statement text changes, for example when columns are
+// reordered.
+//
+//	qe := NewExpectExecutor()
+//	qe.ExpectSelect("users").Where("id", 1).Return([]map[string]interface{}{
+//		{"id": 1, "name": "John Smith"},
+//	})
+//	qe.ExpectUpdate("users").Where("id", 1).Set("name", "Jane Smith")
+//
+//	... run the code being tested ...
+//
+//	qe.AssertExpectations(t)
+//
+// By default expectations can be met in any order, see InOrder. Queries which
+// do not match any expectation return an error and are reported by
+// AssertExpectations.
+type ExpectExecutor struct {
+	mtx          sync.Mutex
+	ordered      bool
+	expectations []expectation
+	unexpected   []string
+}
+
+// NewExpectExecutor creates a query executor with no expectations
+func NewExpectExecutor() *ExpectExecutor {
+	return &ExpectExecutor{}
+}
+
+// InOrder requires the expectations to be met in the order they were added
+func (qe *ExpectExecutor) InOrder() *ExpectExecutor {
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	qe.ordered = true
+	return qe
+}
+
+// ExpectSelect expects a SELECT query on the table
+func (qe *ExpectExecutor) ExpectSelect(table string) *QueryExpectation {
+	return qe.expectQuery(table, SelectQueryType)
+}
+
+// ExpectInsert expects an INSERT query on the table
+func (qe *ExpectExecutor) ExpectInsert(table string) *QueryExpectation {
+	return qe.expectQuery(table, InsertQueryType)
+}
+
+// ExpectUpdate expects an UPDATE query on the table, note that Table.Set
+// generates an UPDATE unless every column is part of the primary key
+func (qe *ExpectExecutor) ExpectUpdate(table string) *QueryExpectation {
+	return qe.expectQuery(table, UpdateQueryType)
+}
+
+// ExpectDelete expects a DELETE query on the table
+func (qe *ExpectExecutor) ExpectDelete(table string) *QueryExpectation {
+	return qe.expectQuery(table, DeleteQueryType)
+}
+
+// ExpectRaw expects a raw query with the given statement, such as the DDL
+// executed by Table.Create
+func (qe *ExpectExecutor) ExpectRaw(stmt string) *QueryExpectation {
+	e := &QueryExpectation{raw: true, statement: stmt, applied: true, times: 1}
+	qe.add(e)
+	return e
+}
+
+// ExpectBatch expects a batch containing queries matching each of the given
+// expectations in order. The query expectations should be created with
+// NewQueryExpectation, rather than one of the Expect methods, so that they are
+// not also expected outside of the batch.
+func (qe *ExpectExecutor) ExpectBatch(queries ...*QueryExpectation) *BatchExpectation {
+	e := &BatchExpectation{queries: queries, applied: true, times: 1}
+	qe.add(e)
+	return e
+}
+
+func (qe *ExpectExecutor) expectQuery(table string, queryType QueryType) *QueryExpectation {
+	e := NewQueryExpectation(table, queryType)
+	qe.add(e)
+	return e
+}
+
+func (qe *ExpectExecutor) add(e expectation) {
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	qe.expectations = append(qe.expectations, e)
+}
+
+// AssertExpectations reports an error for every expectation which has not
+// been met and every query which did not match an expectation. It returns
+// false if any errors were reported.
+func (qe *ExpectExecutor) AssertExpectations(t TestingT) bool {
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	ok := true
+	for _, e := range qe.expectations {
+		if remaining := e.remaining(); remaining > 0 {
+			t.Errorf("Expected %s %d more time(s)", e, remaining)
+			ok = false
+		}
+	}
+	for _, q := range qe.unexpected {
+		t.Errorf("Unexpected %s", q)
+		ok = false
+	}
+
+	return ok
+}
+
+// match finds the expectation matching the call and records it as met
+func (qe *ExpectExecutor) match(matches func(e expectation) bool, describe func() string) (expectation, error) {
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	for _, e := range qe.expectations {
+		if e.remaining() == 0 {
+			continue
+		}
+		if matches(e) {
+			e.met()
+			return e, nil
+		}
+		if qe.ordered {
+			break
+		}
+	}
+
+	desc := describe()
+	qe.unexpected = append(qe.unexpected, desc)
+
+	return nil, fmt.Errorf("Unexpected %s", desc)
+}
+
+func (qe *ExpectExecutor) matchQuery(query QueryGenerator) (*QueryExpectation, error) {
+	e, err := qe.match(func(e expectation) bool {
+		q, ok := e.(*QueryExpectation)
+		return ok && q.matches(query)
+	}, func() string {
+		return describeQuery(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.(*QueryExpectation), nil
+}
+
+func (qe *ExpectExecutor) matchBatch(queries []QueryGenerator) (*BatchExpectation, error) {
+	e, err := qe.match(func(e expectation) bool {
+		b, ok := e.(*BatchExpectation)
+		return ok && b.matches(queries)
+	}, func() string {
+		desc := make([]string, len(queries))
+		for i, q := range queries {
+			desc[i] = describeQuery(q)
+		}
+		return fmt.Sprintf("batch [%s]", strings.Join(desc, ", "))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.(*BatchExpectation), nil
+}
+
+func (qe *ExpectExecutor) QueryOne(query QueryGenerator) (map[string]interface{}, error) {
+	return qe.QueryOneContext(context.Background(), query)
+}
+
+func (qe *ExpectExecutor) QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error) {
+	e, err := qe.matchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if len(e.rows) == 0 {
+		return nil, gocql.ErrNotFound
+	}
+
+	return e.rows[0], nil
+}
+
+func (qe *ExpectExecutor) QueryCAS(query QueryGenerator) (result map[string]interface{}, applied bool, err error) {
+	e, err := qe.matchQuery(query)
+	if err != nil {
+		return nil, false, err
+	}
+	if e.err != nil {
+		return nil, false, e.err
+	}
+
+	result = map[string]interface{}{}
+	if len(e.rows) > 0 {
+		result = e.rows[0]
+	}
+
+	return result, e.applied, nil
+}
+
+func (qe *ExpectExecutor) Query(query QueryGenerator) ([]map[string]interface{}, error) {
+	return qe.QueryContext(context.Background(), query)
+}
+
+func (qe *ExpectExecutor) QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error) {
+	e, err := qe.matchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return e.rows, nil
+}
+
+func (qe *ExpectExecutor) Iter(query QueryGenerator) Iter {
+	return qe.IterContext(context.Background(), query)
+}
+
+func (qe *ExpectExecutor) IterContext(ctx context.Context, query QueryGenerator) Iter {
+	e, err := qe.matchQuery(query)
+	if err != nil {
+		return &mockIter{err: err}
+	}
+
+	return &mockIter{rows: e.rows, err: e.err}
+}
+
+func (qe *ExpectExecutor) Execute(query QueryGenerator) error {
+	return qe.ExecuteContext(context.Background(), query)
+}
+
+func (qe *ExpectExecutor) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	e, err := qe.matchQuery(query)
+	if err != nil {
+		return err
+	}
+
+	return e.err
+}
+
+func (qe *ExpectExecutor) ExecuteBatch(queries []QueryGenerator, options QueryOptions) error {
+	return qe.ExecuteBatchContext(context.Background(), queries, options)
+}
+
+func (qe *ExpectExecutor) ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error {
+	e, err := qe.matchBatch(queries)
+	if err != nil {
+		return err
+	}
+
+	return e.err
+}
+
+func (qe *ExpectExecutor) ExecuteBatchCAS(queries []QueryGenerator, options QueryOptions) (result map[string]interface{}, iter Iter, applied bool, err error) {
+	e, err := qe.matchBatch(queries)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if e.err != nil {
+		return nil, nil, false, e.err
+	}
+
+	return map[string]interface{}{}, &mockIter{}, e.applied, nil
+}
+
+func (qe *ExpectExecutor) Close() {}
+
+type expectation interface {
+	fmt.Stringer
+	remaining() int
+	met()
+}
+
+// A QueryExpectation matches a single query. Only the relations and values
+// given to the expectation are checked, any others in the query are ignored.
+type QueryExpectation struct {
+	table     string
+	queryType QueryType
+	relations []Relation
+	values    map[string]interface{}
+
+	raw       bool
+	statement string
+
+	rows    []map[string]interface{}
+	err     error
+	applied bool
+	times   int
+	calls   int
+}
+
+// NewQueryExpectation creates an expectation for a query on the table which
+// is not registered with an executor, it is used with ExpectBatch.
+func NewQueryExpectation(table string, queryType QueryType) *QueryExpectation {
+	return &QueryExpectation{
+		table:     strings.ToLower(table),
+		queryType: queryType,
+		applied:   true,
+		times:     1,
+	}
+}
+
+// Where expects the query to have an equality relation on the column with
+// the given value
+func (e *QueryExpectation) Where(column string, value interface{}) *QueryExpectation {
+	return e.WhereRelation(Eq(column, value))
+}
+
+// WhereIn expects the query to have an IN relation on the column with the
+// given values
+func (e *QueryExpectation) WhereIn(column string, values ...interface{}) *QueryExpectation {
+	return e.WhereRelation(In(column, values...))
+}
+
+// WhereRelation expects the query to contain the relation
+func (e *QueryExpectation) WhereRelation(relation Relation) *QueryExpectation {
+	e.relations = append(e.relations, relation)
+	return e
+}
+
+// Set expects the query to write the value to the column, the value can be a
+// Modifier
+func (e *QueryExpectation) Set(column string, value interface{}) *QueryExpectation {
+	if e.values == nil {
+		e.values = map[string]interface{}{}
+	}
+	e.values[strings.ToLower(column)] = value
+	return e
+}
+
+// Return sets the rows returned by the query
+func (e *QueryExpectation) Return(rows []map[string]interface{}) *QueryExpectation {
+	e.rows = rows
+	return e
+}
+
+// ReturnError sets the error returned by the query
+func (e *QueryExpectation) ReturnError(err error) *QueryExpectation {
+	e.err = err
+	return e
+}
+
+// Applied sets whether a lightweight transaction was applied, by default it
+// is
+func (e *QueryExpectation) Applied(applied bool) *QueryExpectation {
+	e.applied = applied
+	return e
+}
+
+// Times sets the number of times the query is expected, by default it is
+// expected once
+func (e *QueryExpectation) Times(n int) *QueryExpectation {
+	e.times = n
+	return e
+}
+
+func (e *QueryExpectation) remaining() int {
+	return e.times - e.calls
+}
+
+func (e *QueryExpectation) met() {
+	e.calls++
+}
+
+func (e *QueryExpectation) matches(query QueryGenerator) bool {
+	if e.raw {
+		stmt, _ := query.GenerateStatement()
+		return stmt == e.statement
+	}
+
+	q, ok := query.(Query)
+	if !ok || q.queryType != e.queryType || strings.ToLower(q.table.Name()) != e.table {
+		return false
+	}
+
+	for _, expected := range e.relations {
+		found := false
+		for _, r := range q.relations {
+			if r.relationType == expected.relationType &&
+				strings.ToLower(r.key) == strings.ToLower(expected.key) &&
+				expectedValuesEqual(expected.terms, r.terms) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for column, expected := range e.values {
+		v, ok := q.values[column]
+		if !ok || !expectedValueEqual(expected, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *QueryExpectation) String() string {
+	if e.raw {
+		return fmt.Sprintf("raw query %q", e.statement)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s on %s", e.queryType, e.table)
+	writeRelations(buf, e.relations)
+	writeValues(buf, e.values)
+
+	return buf.String()
+}
+
+// A BatchExpectation matches a batch of queries
+type BatchExpectation struct {
+	queries []*QueryExpectation
+
+	err     error
+	applied bool
+	times   int
+	calls   int
+}
+
+// ReturnError sets the error returned by the batch
+func (e *BatchExpectation) ReturnError(err error) *BatchExpectation {
+	e.err = err
+	return e
+}
+
+// Applied sets whether a conditional batch was applied, by default it is
+func (e *BatchExpectation) Applied(applied bool) *BatchExpectation {
+	e.applied = applied
+	return e
+}
+
+// Times sets the number of times the batch is expected, by default it is
+// expected once
+func (e *BatchExpectation) Times(n int) *BatchExpectation {
+	e.times = n
+	return e
+}
+
+func (e *BatchExpectation) remaining() int {
+	return e.times - e.calls
+}
+
+func (e *BatchExpectation) met() {
+	e.calls++
+}
+
+func (e *BatchExpectation) matches(queries []QueryGenerator) bool {
+	if len(queries) != len(e.queries) {
+		return false
+	}
+	for i, q := range e.queries {
+		if !q.matches(queries[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *BatchExpectation) String() string {
+	desc := make([]string, len(e.queries))
+	for i, q := range e.queries {
+		desc[i] = q.String()
+	}
+
+	return fmt.Sprintf("batch [%s]", strings.Join(desc, ", "))
+}
+
+// describeQuery describes a query in the same format as QueryExpectation
+func describeQuery(query QueryGenerator) string {
+	q, ok := query.(Query)
+	if !ok {
+		stmt, _ := query.GenerateStatement()
+		return fmt.Sprintf("raw query %q", stmt)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s on %s", q.queryType, q.table.Name())
+	writeRelations(buf, q.relations)
+	writeValues(buf, q.values)
+
+	return buf.String()
+}
+
+func writeRelations(buf *bytes.Buffer, relations []Relation) {
+	for i, r := range relations {
+		if i == 0 {
+			buf.WriteString(" where ")
+		} else {
+			buf.WriteString(" and ")
+		}
+		cql, _ := r.generateCQL()
+		if r.relationType == relationTypeIN {
+			buf.WriteString(strings.Replace(cql, "?", fmt.Sprintf("%#v", r.terms), 1))
+		} else {
+			buf.WriteString(strings.Replace(cql, "?", fmt.Sprintf("%#v", r.terms[0]), 1))
+		}
+	}
+}
+
+func writeValues(buf *bytes.Buffer, values map[string]interface{}) {
+	names := Query{values: values}.valueNames()
+	for i, k := range names {
+		if i == 0 {
+			buf.WriteString(" setting ")
+		} else {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(buf, "%s = %#v", k, values[k])
+	}
+}
+
+func expectedValuesEqual(expected, actual []interface{}) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if !expectedValueEqual(expected[i], actual[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// expectedValueEqual compares values allowing numbers of different types to
+// match, so that Where("id", 1) matches an int64 id
+func expectedValueEqual(expected, actual interface{}) bool {
+	if reflect.DeepEqual(expected, actual) {
+		return true
+	}
+	if expected == nil || actual == nil {
+		return false
+	}
+	if _, ok := expected.(Modifier); ok {
+		return false
+	}
+
+	c, err := compareValues(expected, actual)
+	return err == nil && c == 0
+}
@@ -0,0 +1,107 @@
+package gocassa
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpectExecutor(t *testing.T) {
+	qe := NewExpectExecutor()
+	qe.ExpectSelect("test").Where("fielda", "a").Return([]map[string]interface{}{
+		{"fielda": "a", "fieldb": "b"},
+	})
+	qe.ExpectUpdate("test").Where("fielda", "a").Set("fieldb", "c")
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	assert.Nil(t, tbl.Set(Document{FieldA: "a", FieldB: "c"}).Execute())
+
+	doc := Document{}
+	assert.Nil(t, tbl.Where(Eq("fielda", "a")).Read().ScanOne(&doc))
+	assert.Equal(t, Document{FieldA: "a", FieldB: "b"}, doc)
+
+	qe.AssertExpectations(t)
+}
+
+func TestExpectExecutor_numbers(t *testing.T) {
+	qe := NewExpectExecutor()
+	qe.ExpectSelect("test").WhereIn("fielda", 1, 2).Return([]map[string]interface{}{
+		{"fielda": int64(1)},
+	})
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", TypeDocument{}, []string{"fielda"}, nil, nil)
+
+	docs := []TypeDocument{}
+	assert.Nil(t, tbl.Where(In("fielda", int64(1), int64(2))).Read().Scan(&docs))
+	assert.Len(t, docs, 1)
+
+	qe.AssertExpectations(t)
+}
+
+func TestExpectExecutor_unmet(t *testing.T) {
+	qe := NewExpectExecutor()
+	qe.ExpectDelete("test").Where("fielda", "a")
+	qe.ExpectRaw("TRUNCATE TABLE test.test")
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	assert.Nil(t, tbl.Truncate())
+	assert.NotNil(t, tbl.Where(Eq("fielda", "b")).Delete().Execute())
+
+	rt := &recordingT{}
+	assert.False(t, qe.AssertExpectations(rt))
+	assert.Equal(t, []string{
+		`Expected delete on test where fielda = "a" 1 more time(s)`,
+		`Unexpected delete on test where fielda = "b"`,
+	}, rt.errors)
+}
+
+func TestExpectExecutor_inOrder(t *testing.T) {
+	qe := NewExpectExecutor().InOrder()
+	qe.ExpectDelete("test").Where("fielda", "a")
+	qe.ExpectDelete("test").Where("fielda", "b")
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	assert.NotNil(t, tbl.Where(Eq("fielda", "b")).Delete().Execute())
+	assert.Nil(t, tbl.Where(Eq("fielda", "a")).Delete().Execute())
+	assert.Nil(t, tbl.Where(Eq("fielda", "b")).Delete().Execute())
+
+	rt := &recordingT{}
+	assert.False(t, qe.AssertExpectations(rt))
+	assert.Len(t, rt.errors, 1)
+}
+
+func TestExpectExecutor_batch(t *testing.T) {
+	qe := NewExpectExecutor()
+	qe.ExpectBatch(
+		NewQueryExpectation("test", UpdateQueryType).Where("fielda", "a"),
+		NewQueryExpectation("test", DeleteQueryType).Where("fielda", "b"),
+	).ReturnError(errors.New("Timeout"))
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	err := MultiQuery(
+		tbl.Set(Document{FieldA: "a", FieldB: "b"}),
+		tbl.Where(Eq("fielda", "b")).Delete(),
+	).ExecuteBatch()
+	assert.EqualError(t, err, "Timeout")
+
+	qe.AssertExpectations(t)
+}
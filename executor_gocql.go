@@ -1,52 +1,86 @@
 package gocassa
 
 import (
-	"sync"
-
-	"github.com/Sirupsen/logrus"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/dancannon/gocassa/encoding"
 	"github.com/gocql/gocql"
 )
 
 // Connect uses the given gocql cluster configuration to connect to a Cassandra
 // cluster using the built-in GoCQL query executor. If you wish to use your
 // own query executor then use NewConnection.
-func Connect(config *gocql.ClusterConfig) (QueryExecutor, error) {
+//
+// The executor can optionally be configured with ExecutorOptions, see
+// NewGoCQLExecutor.
+func Connect(config *gocql.ClusterConfig, options ...*ExecutorOptions) (QueryExecutor, error) {
 	session, err := config.CreateSession()
 	if err != nil {
 		return nil, err
 	}
 
-	return NewGoCQLExecutor(session), nil
+	return NewGoCQLExecutor(session, options...), nil
 }
 
-// NewGoCQLExecutor creates a GoCQL query executor using the given GoCQL session
-func NewGoCQLExecutor(session *gocql.Session) QueryExecutor {
+// NewGoCQLExecutor creates a GoCQL query executor using the given GoCQL session.
+// The executor can optionally be configured with ExecutorOptions, if several
+// are given the last one which is not nil is used. The options are copied so
+// they can be reused by the caller.
+func NewGoCQLExecutor(session *gocql.Session, options ...*ExecutorOptions) QueryExecutor {
+	var opts ExecutorOptions
+	for _, o := range options {
+		if o != nil {
+			opts = *o
+		}
+	}
+	if opts.Logger == nil {
+		opts.Logger = NopLogger
+	}
+
 	return gocqlExecutor{
 		session: session,
+		options: opts,
+		async:   newAsyncPool(opts.AsyncConcurrency),
 	}
 }
 
 type gocqlExecutor struct {
 	session *gocql.Session
+	options ExecutorOptions
+	async   *asyncPool
 }
 
 func (qe gocqlExecutor) QueryOne(query QueryGenerator) (map[string]interface{}, error) {
-	cqlQuery := qe.createCQLQuery(query)
+	return qe.QueryOneContext(context.Background(), query)
+}
+
+func (qe gocqlExecutor) QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error) {
+	ctx, done := observeQuery(ctx, qe.options.Observer, OperationQuery, query)
+	cqlQuery, cancel := qe.createCQLQuery(ctx, query)
+	defer cancel()
 
 	m := map[string]interface{}{}
 	if err := cqlQuery.MapScan(m); err != nil {
+		done(0, cqlQuery.Attempts(), err)
 		return nil, err
 	}
 
+	done(1, cqlQuery.Attempts(), nil)
 	return m, nil
 }
 
 func (qe gocqlExecutor) QueryCAS(query QueryGenerator) (result map[string]interface{}, applied bool, err error) {
-	cqlQuery := qe.createCQLQuery(query)
+	ctx, done := observeQuery(context.Background(), qe.options.Observer, OperationQuery, query)
+	cqlQuery, cancel := qe.createCQLQuery(ctx, query)
+	defer cancel()
 
 	m := map[string]interface{}{}
 	applied, err = cqlQuery.MapScanCAS(m)
+	done(len(m), cqlQuery.Attempts(), err)
 	if err != nil {
 		return nil, false, err
 	}
@@ -55,7 +89,13 @@ func (qe gocqlExecutor) QueryCAS(query QueryGenerator) (result map[string]interf
 }
 
 func (qe gocqlExecutor) Query(query QueryGenerator) ([]map[string]interface{}, error) {
-	cqlQuery := qe.createCQLQuery(query)
+	return qe.QueryContext(context.Background(), query)
+}
+
+func (qe gocqlExecutor) QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error) {
+	ctx, done := observeQuery(ctx, qe.options.Observer, OperationQuery, query)
+	cqlQuery, cancel := qe.createCQLQuery(ctx, query)
+	defer cancel()
 
 	iter := cqlQuery.Iter()
 	ret := []map[string]interface{}{}
@@ -67,28 +107,60 @@ func (qe gocqlExecutor) Query(query QueryGenerator) ([]map[string]interface{}, e
 		m = map[string]interface{}{}
 	}
 
-	return ret, iter.Close()
+	err := iter.Close()
+	done(len(ret), cqlQuery.Attempts(), err)
+
+	return ret, err
 }
 
 func (qe gocqlExecutor) Iter(query QueryGenerator) Iter {
-	cqlQuery := qe.createCQLQuery(query)
+	return qe.IterContext(context.Background(), query)
+}
 
-	return gocqlIter{
-		iter: cqlQuery.Iter(),
+func (qe gocqlExecutor) IterContext(ctx context.Context, query QueryGenerator) Iter {
+	ctx, done := observeQuery(ctx, qe.options.Observer, OperationIter, query)
+	cqlQuery, cancel := qe.createCQLQuery(ctx, query)
+
+	// The timeout covers fetching every page so the context is only cancelled
+	// once the iterator is closed
+	iter := newGoCQLIter(cqlQuery.Iter())
+	iter.done = func(rows int, err error) {
+		cancel()
+		done(rows, cqlQuery.Attempts(), err)
 	}
+
+	return iter
 }
 
 // Query executes a query and returns the results.
 func (qe gocqlExecutor) Execute(query QueryGenerator) error {
-	cqlQuery := qe.createCQLQuery(query)
+	return qe.ExecuteContext(context.Background(), query)
+}
 
-	return cqlQuery.Exec()
+func (qe gocqlExecutor) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	ctx, done := observeQuery(ctx, qe.options.Observer, OperationExecute, query)
+	cqlQuery, cancel := qe.createCQLQuery(ctx, query)
+	defer cancel()
+
+	err := cqlQuery.Exec()
+	done(0, cqlQuery.Attempts(), err)
+
+	return err
 }
 
 func (qe gocqlExecutor) ExecuteBatch(queries []QueryGenerator, options QueryOptions) error {
-	batch := qe.createCQLBatch(queries, options)
+	return qe.ExecuteBatchContext(context.Background(), queries, options)
+}
 
-	return qe.session.ExecuteBatch(batch)
+func (qe gocqlExecutor) ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error {
+	ctx, done := observeBatch(ctx, qe.options.Observer, queries)
+	batch, cancel := qe.createCQLBatch(ctx, queries, options)
+	defer cancel()
+
+	err := qe.session.ExecuteBatch(batch)
+	done(0, batch.Attempts(), err)
+
+	return err
 }
 
 func (qe gocqlExecutor) ExecuteBatchCAS(
@@ -96,74 +168,177 @@ func (qe gocqlExecutor) ExecuteBatchCAS(
 ) (
 	result map[string]interface{}, iter Iter, applied bool, err error,
 ) {
-	batch := qe.createCQLBatch(queries, options)
+	ctx, done := observeBatch(context.Background(), qe.options.Observer, queries)
+	batch, cancel := qe.createCQLBatch(ctx, queries, options)
+	defer cancel()
 
+	result = map[string]interface{}{}
 	applied, cqlIter, err := qe.session.MapExecuteBatchCAS(batch, result)
+	done(len(result), batch.Attempts(), err)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return result, newGoCQLIter(cqlIter), applied, nil
+}
 
-	return result, gocqlIter{
-		iter: cqlIter,
-		err:  err,
-	}, applied, err
+// Go runs fn in the worker pool of the executor, see AsyncExecutor
+func (qe gocqlExecutor) Go(ctx context.Context, fn func()) error {
+	return qe.async.Go(ctx, fn)
 }
 
+// Close waits for any asynchronous queries to finish and closes the session
 func (qe gocqlExecutor) Close() {
+	qe.async.close()
 	qe.session.Close()
 }
 
-func (qe *gocqlExecutor) createCQLQuery(query QueryGenerator) *gocql.Query {
+// createCQLQuery builds the gocql query, the returned function must be called
+// once the query has completed to release the context used for the timeout.
+func (qe *gocqlExecutor) createCQLQuery(ctx context.Context, query QueryGenerator) (*gocql.Query, context.CancelFunc) {
 	stmt, vals := query.GenerateStatement()
+	logStatement(qe.options, "Executing query", query, stmt, vals)
 
-	logrus.WithFields(logrus.Fields{
-		"values": vals,
-	}).Infof("Executing query: %s", stmt)
+	options := query.Options()
+	ctx, cancel := withTimeout(ctx, options.Timeout)
 
-	cqlQuery := qe.session.Query(stmt, vals...)
-	if query.Options().Consistency != nil {
-		cqlQuery = cqlQuery.Consistency(*query.Options().Consistency)
+	cqlQuery := qe.session.Query(stmt, vals...).WithContext(ctx)
+	if options.Consistency != nil {
+		cqlQuery = cqlQuery.Consistency(*options.Consistency)
+	}
+	if options.PageSize > 0 {
+		cqlQuery = cqlQuery.PageSize(options.PageSize)
+	}
+	if options.PageState != nil {
+		cqlQuery = cqlQuery.PageState(options.PageState)
 	}
+	if options.RetryPolicy != nil {
+		cqlQuery = cqlQuery.RetryPolicy(options.RetryPolicy)
+	}
+	if options.SpeculativeExecution != nil {
+		cqlQuery = cqlQuery.SetSpeculativeExecutionPolicy(options.SpeculativeExecution)
+	}
+	cqlQuery = cqlQuery.Idempotent(isIdempotent(query, options))
 
-	return cqlQuery
+	return cqlQuery, cancel
 }
 
-func (qe *gocqlExecutor) createCQLBatch(queries []QueryGenerator, options QueryOptions) *gocql.Batch {
-	batch := gocql.NewBatch(options.BatchType)
+// createCQLBatch builds the gocql batch, the returned function must be called
+// once the batch has completed to release the context used for the timeout.
+func (qe *gocqlExecutor) createCQLBatch(ctx context.Context, queries []QueryGenerator, options QueryOptions) (*gocql.Batch, context.CancelFunc) {
+	ctx, cancel := withTimeout(ctx, options.Timeout)
+
+	batch := gocql.NewBatch(options.BatchType).WithContext(ctx)
 	if options.Consistency != nil {
 		batch.Cons = *options.Consistency
 	}
 	if options.SerialConsistency != nil {
 		batch = batch.SerialConsistency(*options.SerialConsistency)
 	}
+	if options.RetryPolicy != nil {
+		batch = batch.RetryPolicy(options.RetryPolicy)
+	}
+	if options.SpeculativeExecution != nil {
+		batch = batch.SpeculativeExecutionPolicy(options.SpeculativeExecution)
+	}
 
 	for _, query := range queries {
 		stmt, vals := query.GenerateStatement()
+		logStatement(qe.options, "Adding query to batch", query, stmt, vals)
+
+		// The batch is only idempotent, and so retried, if every query is
+		batch.Entries = append(batch.Entries, gocql.BatchEntry{
+			Stmt:       stmt,
+			Args:       vals,
+			Idempotent: isIdempotent(query, options),
+		})
+	}
 
-		logrus.WithFields(logrus.Fields{
-			"values": vals,
-		}).Infof("Adding query to batch: %s", stmt)
+	return batch, cancel
+}
 
-		batch.Query(stmt, vals...)
+// An idempotentQuery is a query which knows whether it can be safely retried
+type idempotentQuery interface {
+	isIdempotent() bool
+}
+
+// isIdempotent returns the idempotency set in the options if any, otherwise it
+// is inferred from the query.
+func isIdempotent(query QueryGenerator, options QueryOptions) bool {
+	if options.Idempotent != nil {
+		return *options.Idempotent
+	}
+	if q, ok := query.(idempotentQuery); ok {
+		return q.isIdempotent()
 	}
 
-	return batch
+	return false
 }
 
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// gocqlRows is the part of *gocql.Iter used by gocqlIter
+type gocqlRows interface {
+	Columns() []gocql.ColumnInfo
+	Scan(dest ...interface{}) bool
+	MapScan(m map[string]interface{}) bool
+	NumRows() int
+	WillSwitchPage() bool
+	PageState() []byte
+	GetCustomPayload() map[string][]byte
+	Close() error
+}
+
+// gocqlIter scans rows directly into struct destinations, falling back to
+// decoding each row through a reusable map for any other destination. It is
+// not safe for concurrent use.
 type gocqlIter struct {
-	mtx  sync.Mutex
-	iter *gocql.Iter
+	iter gocqlRows
+	row  map[string]interface{}
 	err  error
+
+	// plan binds the result columns to the fields of planType, it is built on
+	// the first scan into a struct of that type
+	planType reflect.Type
+	plan     *scanPlan
+
+	// rows counts the rows scanned, it is passed to done when the iterator
+	// is closed
+	rows int
+	done func(rows int, err error)
+}
+
+func newGoCQLIter(iter gocqlRows) *gocqlIter {
+	return &gocqlIter{
+		iter: iter,
+		row:  map[string]interface{}{},
+	}
 }
 
-func (iter gocqlIter) Scan(dest interface{}) bool {
-	var m map[string]interface{}
+func (iter *gocqlIter) Scan(dest interface{}) bool {
+	if iter.err != nil {
+		return false
+	}
+
+	if v, t, ok := structDest(dest); ok {
+		if plan := iter.scanPlan(t); plan != nil {
+			return iter.scanStruct(plan, v)
+		}
+	}
 
-	if ok := iter.iter.MapScan(m); !ok {
+	if ok := iter.iter.MapScan(iter.row); !ok {
 		return false
 	}
 
-	if err := decodeResult(m, dest); err != nil {
-		iter.mtx.Lock()
+	iter.rows++
+	if err := decodeRow(iter.row, dest); err != nil {
 		iter.err = err
-		iter.mtx.Unlock()
 
 		return false
 	}
@@ -171,25 +346,200 @@ func (iter gocqlIter) Scan(dest interface{}) bool {
 	return true
 }
 
-func (iter gocqlIter) NumRows() int {
+// scanStruct scans the next row directly into the fields of the struct v, v
+// can also be a pointer to a struct which is allocated if nil
+func (iter *gocqlIter) scanStruct(plan *scanPlan, v reflect.Value) bool {
+	// Only set a nil struct pointer once a row has been read
+	var ptr reflect.Value
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			ptr = v
+			v = reflect.New(v.Type().Elem())
+		}
+		v = v.Elem()
+	}
+
+	for i, column := range plan.columns {
+		if column.direct {
+			plan.dest[i] = encoding.FieldByIndex(v, column.index).Addr().Interface()
+		}
+	}
+
+	if ok := iter.iter.Scan(plan.dest...); !ok {
+		return false
+	}
+
+	iter.rows++
+	for _, index := range plan.zero {
+		field := encoding.FieldByIndex(v, index)
+		field.Set(reflect.Zero(field.Type()))
+	}
+	for i, column := range plan.columns {
+		if column.direct || column.index == nil {
+			continue
+		}
+
+		value := reflect.ValueOf(plan.dest[i]).Elem().Interface()
+		if err := decodeValue(value, encoding.FieldByIndex(v, column.index)); err != nil {
+			iter.err = fmt.Errorf("Cannot decode column %s: %v", column.name, err)
+
+			return false
+		}
+	}
+	if ptr.IsValid() {
+		ptr.Set(v.Addr())
+	}
+
+	return true
+}
+
+// scanPlan returns the plan for scanning rows into structs of type t, or nil
+// if rows must be decoded through a map
+func (iter *gocqlIter) scanPlan(t reflect.Type) *scanPlan {
+	if iter.planType != t {
+		iter.planType = t
+		iter.plan = newScanPlan(t, iter.iter.Columns())
+	}
+
+	return iter.plan
+}
+
+// A scanPlan binds the columns of a result to the fields of a struct type.
+// Columns whose type gocql unmarshals into the field type, including fields
+// implementing gocql.Unmarshaler, are scanned straight into the field, any
+// other column is scanned into a value of the default type of the column and
+// then converted like decodeRow would.
+type scanPlan struct {
+	columns []scanColumn
+	// zero holds the index of each field which is not scanned directly, they
+	// are reset for every row like decodeRow does
+	zero [][]int
+	// dest holds the scan destination of each column, destinations of
+	// direct columns are replaced with the field address for every row
+	dest []interface{}
+}
+
+type scanColumn struct {
+	name string
+	// index is the field index of the column, nil if there is no field
+	index  []int
+	direct bool
+}
+
+func newScanPlan(t reflect.Type, columns []gocql.ColumnInfo) *scanPlan {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	fields := cachedDecodePlan(t).fields
+	plan := &scanPlan{
+		columns: make([]scanColumn, len(columns)),
+		dest:    make([]interface{}, len(columns)),
+	}
+	for i, column := range columns {
+		// Tuples are scanned into several destinations, leave them to the
+		// map path
+		if column.TypeInfo.Type() == gocql.TypeTuple {
+			return nil
+		}
+
+		value, err := column.TypeInfo.NewWithError()
+		if err != nil {
+			return nil
+		}
+
+		index, ok := fields[column.Name]
+		if !ok {
+			index, ok = fields[strings.ToLower(column.Name)]
+		}
+
+		plan.dest[i] = value
+		plan.columns[i] = scanColumn{
+			name:   column.Name,
+			index:  index,
+			direct: ok && scanDirect(fieldByIndexType(t, index), reflect.TypeOf(value).Elem()),
+		}
+	}
+
+	direct := map[string]bool{}
+	for _, column := range plan.columns {
+		if column.direct {
+			direct[fmt.Sprint(column.index)] = true
+		}
+	}
+	for _, index := range fields {
+		if !direct[fmt.Sprint(index)] {
+			plan.zero = append(plan.zero, index)
+		}
+	}
+
+	return plan
+}
+
+// scanDirect returns true if gocql can scan a column whose default type is
+// columnType straight into a field of type fieldType
+func scanDirect(fieldType, columnType reflect.Type) bool {
+	return fieldType == columnType || reflect.PtrTo(fieldType).Implements(gocqlUnmarshalerType)
+}
+
+var gocqlUnmarshalerType = reflect.TypeOf((*gocql.Unmarshaler)(nil)).Elem()
+
+// fieldByIndexType returns the type of the field with the given index
+// sequence, following pointers to embedded structs
+func fieldByIndexType(t reflect.Type, index []int) reflect.Type {
+	for _, i := range index {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		t = t.Field(i).Type
+	}
+
+	return t
+}
+
+// structDest returns the value pointed at by dest and the struct type if dest
+// is a pointer to a struct or a pointer to a pointer to a struct
+func structDest(dest interface{}) (reflect.Value, reflect.Type, bool) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return reflect.Value{}, nil, false
+	}
+
+	v = v.Elem()
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return v, t, t.Kind() == reflect.Struct
+}
+
+func (iter *gocqlIter) NumRows() int {
 	return iter.iter.NumRows()
 }
 
-func (iter gocqlIter) WillSwitchPage() bool {
+func (iter *gocqlIter) WillSwitchPage() bool {
 	return iter.iter.WillSwitchPage()
 }
 
-func (iter gocqlIter) GetCustomPayload() map[string][]byte {
+func (iter *gocqlIter) PageState() []byte {
+	return iter.iter.PageState()
+}
+
+func (iter *gocqlIter) GetCustomPayload() map[string][]byte {
 	return iter.iter.GetCustomPayload()
 }
 
-func (iter gocqlIter) Close() error {
-	if err := iter.iter.Close(); err != nil {
-		return err
+func (iter *gocqlIter) Close() error {
+	err := iter.iter.Close()
+	if err == nil {
+		err = iter.err
 	}
 
-	iter.mtx.Lock()
-	defer iter.mtx.Unlock()
+	if iter.done != nil {
+		iter.done(iter.rows, err)
+		iter.done = nil
+	}
 
-	return iter.err
+	return err
 }
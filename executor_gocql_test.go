@@ -0,0 +1,199 @@
+package gocassa
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGoCQLRows returns rows of marshalled columns, unmarshalling them the
+// same way gocql does
+type fakeGoCQLRows struct {
+	columns []gocql.ColumnInfo
+	rows    [][][]byte
+	pos     int
+	err     error
+}
+
+func newFakeGoCQLRows(columns []gocql.ColumnInfo, rows ...[]interface{}) *fakeGoCQLRows {
+	f := &fakeGoCQLRows{columns: columns}
+	for _, row := range rows {
+		data := make([][]byte, len(row))
+		for i, v := range row {
+			b, err := gocql.Marshal(columns[i].TypeInfo, v)
+			if err != nil {
+				panic(err)
+			}
+			data[i] = b
+		}
+		f.rows = append(f.rows, data)
+	}
+
+	return f
+}
+
+func (f *fakeGoCQLRows) Columns() []gocql.ColumnInfo {
+	return f.columns
+}
+
+func (f *fakeGoCQLRows) Scan(dest ...interface{}) bool {
+	if f.err != nil || f.pos >= len(f.rows) {
+		return false
+	}
+
+	for i, column := range f.columns {
+		if err := gocql.Unmarshal(column.TypeInfo, f.rows[f.pos][i], dest[i]); err != nil {
+			f.err = err
+			return false
+		}
+	}
+	f.pos++
+
+	return true
+}
+
+func (f *fakeGoCQLRows) MapScan(m map[string]interface{}) bool {
+	dest := make([]interface{}, len(f.columns))
+	for i, column := range f.columns {
+		dest[i] = column.TypeInfo.New()
+	}
+	if !f.Scan(dest...) {
+		return false
+	}
+
+	for i, column := range f.columns {
+		m[column.Name] = reflect.ValueOf(dest[i]).Elem().Interface()
+	}
+
+	return true
+}
+
+func (f *fakeGoCQLRows) NumRows() int                        { return len(f.rows) }
+func (f *fakeGoCQLRows) WillSwitchPage() bool                { return false }
+func (f *fakeGoCQLRows) PageState() []byte                   { return nil }
+func (f *fakeGoCQLRows) GetCustomPayload() map[string][]byte { return nil }
+func (f *fakeGoCQLRows) Close() error                        { return f.err }
+
+type scanDocument struct {
+	ID      string
+	Count   int64
+	Small   int8 // scanned through a value of the column type
+	Created time.Time
+	Tags    []string
+	Other   string
+}
+
+func scanColumns() []gocql.ColumnInfo {
+	native := func(typ gocql.Type) gocql.NativeType {
+		return gocql.NewNativeType(4, typ, "")
+	}
+
+	return []gocql.ColumnInfo{
+		{Name: "id", TypeInfo: native(gocql.TypeVarchar)},
+		{Name: "count", TypeInfo: native(gocql.TypeBigInt)},
+		{Name: "small", TypeInfo: native(gocql.TypeInt)},
+		{Name: "created", TypeInfo: native(gocql.TypeTimestamp)},
+		{Name: "tags", TypeInfo: gocql.CollectionType{
+			NativeType: native(gocql.TypeList),
+			Elem:       native(gocql.TypeVarchar),
+		}},
+		{Name: "ignored", TypeInfo: native(gocql.TypeBoolean)},
+	}
+}
+
+func TestGoCQLIterScan_struct(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	iter := newGoCQLIter(newFakeGoCQLRows(scanColumns(),
+		[]interface{}{"a", int64(1), 2, created, []string{"x", "y"}, true},
+		[]interface{}{"b", nil, 3, nil, nil, false},
+	))
+
+	doc := scanDocument{Other: "reset"}
+	assert.True(t, iter.Scan(&doc))
+	assert.Equal(t, scanDocument{ID: "a", Count: 1, Small: 2, Created: created, Tags: []string{"x", "y"}}, doc)
+	plan := iter.plan
+	if assert.NotNil(t, plan) {
+		assert.True(t, plan.columns[0].direct)
+		assert.False(t, plan.columns[2].direct)
+		assert.Nil(t, plan.columns[5].index)
+	}
+
+	var ptr *scanDocument
+	assert.True(t, iter.Scan(&ptr))
+	if assert.NotNil(t, ptr) {
+		assert.Equal(t, "b", ptr.ID)
+		assert.Equal(t, int64(0), ptr.Count)
+		assert.Equal(t, int8(3), ptr.Small)
+		assert.True(t, ptr.Created.IsZero())
+		assert.Empty(t, ptr.Tags)
+	}
+	assert.True(t, plan == iter.plan, "the plan is reused")
+
+	ptr = nil
+	assert.False(t, iter.Scan(&ptr))
+	assert.Nil(t, ptr, "nil pointers are left alone at the end of the rows")
+	assert.Nil(t, iter.Close())
+}
+
+func TestGoCQLIterScan_map(t *testing.T) {
+	iter := newGoCQLIter(newFakeGoCQLRows(scanColumns(),
+		[]interface{}{"a", int64(1), 2, time.Time{}, []string{"x"}, true},
+	))
+
+	row := map[string]interface{}{}
+	assert.True(t, iter.Scan(row))
+	assert.Equal(t, "a", row["id"])
+	assert.Equal(t, []string{"x"}, row["tags"])
+	assert.Nil(t, iter.plan)
+}
+
+func benchmarkGoCQLIterScan(b *testing.B, scan func(iter *gocqlIter) bool) {
+	created := time.Now()
+	rows := make([][]interface{}, 100)
+	for i := range rows {
+		rows[i] = []interface{}{"id", int64(i), i, created, []string{"a", "b"}, true}
+	}
+	fake := newFakeGoCQLRows(scanColumns(), rows...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fake.pos = 0
+		iter := newGoCQLIter(fake)
+		for scan(iter) {
+		}
+	}
+}
+
+func BenchmarkGoCQLIterScan_direct(b *testing.B) {
+	doc := scanDocument{}
+	benchmarkGoCQLIterScan(b, func(iter *gocqlIter) bool {
+		return iter.Scan(&doc)
+	})
+}
+
+// BenchmarkGoCQLIterScan_map measures decoding structs through a map, as rows
+// were decoded before structs were scanned directly
+func BenchmarkGoCQLIterScan_map(b *testing.B) {
+	doc := scanDocument{}
+	benchmarkGoCQLIterScan(b, func(iter *gocqlIter) bool {
+		if !iter.iter.MapScan(iter.row) {
+			return false
+		}
+		return decodeRow(iter.row, &doc) == nil
+	})
+}
+
+func TestNewGoCQLExecutor_options(t *testing.T) {
+	qe := NewGoCQLExecutor(nil).(gocqlExecutor)
+	assert.Equal(t, NopLogger, qe.options.Logger)
+
+	options := &ExecutorOptions{AsyncConcurrency: 2}
+	qe = NewGoCQLExecutor(nil, options).(gocqlExecutor)
+	assert.Equal(t, NopLogger, qe.options.Logger)
+	assert.Equal(t, 2, cap(qe.async.sem))
+	assert.Nil(t, options.Logger, "the caller's options are not modified")
+}
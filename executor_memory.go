@@ -0,0 +1,1058 @@
+package gocassa
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dancannon/gocassa/encoding"
+	"github.com/gocql/gocql"
+)
+
+// ErrUnsupportedByMemoryExecutor is returned by the in-memory query executor
+// for queries which it cannot interpret.
+var ErrUnsupportedByMemoryExecutor = errors.New("Query not supported by the memory executor")
+
+// memorySchemaVersion is reported by system.local so that
+// Keyspace.AwaitSchemaAgreement succeeds immediately
+var memorySchemaVersion = gocql.UUID{0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79}
+
+// NewMemoryExecutor creates a query executor which stores rows in memory
+// rather than sending queries to Cassandra, allowing tables to be used in unit
+// tests without a Cassandra node.
+//
+// Rather than parsing CQL the executor interprets the queries built by Query,
+// so rows are stored per partition and kept in clustering order, relations
+// are evaluated against each row and modifiers, TTLs and write timestamps are
+// applied as Cassandra would. Partitions are returned ordered by their key
+// rather than by token. Lightweight transactions always apply as queries built
+// by Query cannot contain conditions.
+//
+// Raw queries are limited to the DDL statements generated by Keyspace and
+// Table, creating and altering is a no-op while dropping and truncating
+// removes the stored rows. Any other raw query returns
+// ErrUnsupportedByMemoryExecutor.
+func NewMemoryExecutor() QueryExecutor {
+	return &memoryExecutor{
+		tables: map[string]*memoryTable{},
+		now:    time.Now,
+		async:  newAsyncPool(0),
+	}
+}
+
+type memoryExecutor struct {
+	mtx    sync.RWMutex
+	tables map[string]*memoryTable
+	now    func() time.Time
+	async  *asyncPool
+}
+
+func (qe *memoryExecutor) QueryOne(query QueryGenerator) (map[string]interface{}, error) {
+	return qe.QueryOneContext(context.Background(), query)
+}
+
+func (qe *memoryExecutor) QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error) {
+	rows, err := qe.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, gocql.ErrNotFound
+	}
+
+	return rows[0], nil
+}
+
+func (qe *memoryExecutor) QueryCAS(query QueryGenerator) (result map[string]interface{}, applied bool, err error) {
+	if err := qe.Execute(query); err != nil {
+		return nil, false, err
+	}
+
+	return map[string]interface{}{}, true, nil
+}
+
+func (qe *memoryExecutor) Query(query QueryGenerator) ([]map[string]interface{}, error) {
+	return qe.QueryContext(context.Background(), query)
+}
+
+func (qe *memoryExecutor) QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error) {
+	iter := qe.IterContext(ctx, query).(*rowsIter)
+	if iter.err != nil {
+		return nil, iter.err
+	}
+
+	return iter.rows, nil
+}
+
+func (qe *memoryExecutor) Iter(query QueryGenerator) Iter {
+	return qe.IterContext(context.Background(), query)
+}
+
+func (qe *memoryExecutor) IterContext(ctx context.Context, query QueryGenerator) Iter {
+	if err := ctx.Err(); err != nil {
+		return &rowsIter{err: err}
+	}
+
+	var rows []map[string]interface{}
+	var err error
+	switch q := query.(type) {
+	case Query:
+		if q.queryType != SelectQueryType {
+			return &rowsIter{err: qe.ExecuteContext(ctx, q)}
+		}
+		rows, err = qe.selectRows(q)
+	default:
+		rows, err = qe.queryRaw(query)
+	}
+	if err != nil {
+		return &rowsIter{err: err}
+	}
+
+	return newRowsIter(rows, query.Options())
+}
+
+func (qe *memoryExecutor) Execute(query QueryGenerator) error {
+	return qe.ExecuteContext(context.Background(), query)
+}
+
+func (qe *memoryExecutor) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	return qe.ExecuteBatchContext(ctx, []QueryGenerator{query}, query.Options())
+}
+
+func (qe *memoryExecutor) ExecuteBatch(queries []QueryGenerator, options QueryOptions) error {
+	return qe.ExecuteBatchContext(context.Background(), queries, options)
+}
+
+// ExecuteBatchContext checks every query in the batch before applying any of
+// them so that an invalid query does not leave the batch partially applied.
+func (qe *memoryExecutor) ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	now := qe.now()
+	writes := make([]func() error, 0, len(queries))
+	for _, query := range queries {
+		var write func() error
+		var err error
+		switch q := query.(type) {
+		case Query:
+			write, err = qe.prepareWrite(q, now)
+		default:
+			write, err = qe.prepareRaw(query)
+		}
+		if err != nil {
+			return err
+		}
+
+		writes = append(writes, write)
+	}
+
+	for _, write := range writes {
+		if err := write(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (qe *memoryExecutor) ExecuteBatchCAS(queries []QueryGenerator, options QueryOptions) (result map[string]interface{}, iter Iter, applied bool, err error) {
+	if err := qe.ExecuteBatch(queries, options); err != nil {
+		return nil, nil, false, err
+	}
+
+	return map[string]interface{}{}, &rowsIter{}, true, nil
+}
+
+func (qe *memoryExecutor) Go(ctx context.Context, fn func()) error {
+	return qe.async.Go(ctx, fn)
+}
+
+// Close waits for any asynchronous queries to finish, the stored rows are kept
+func (qe *memoryExecutor) Close() {
+	qe.async.close()
+}
+
+// queryRaw answers the schema queries made by Keyspace.AwaitSchemaAgreement
+func (qe *memoryExecutor) queryRaw(query QueryGenerator) ([]map[string]interface{}, error) {
+	stmt, _ := query.GenerateStatement()
+	switch stmt {
+	case "SELECT schema_version FROM system.local":
+		return []map[string]interface{}{{"schema_version": memorySchemaVersion}}, nil
+	case "SELECT schema_version FROM system.peers":
+		return []map[string]interface{}{}, nil
+	}
+
+	return nil, ErrUnsupportedByMemoryExecutor
+}
+
+// prepareRaw interprets the DDL statements generated by Keyspace and Table
+func (qe *memoryExecutor) prepareRaw(query QueryGenerator) (func() error, error) {
+	stmt, _ := query.GenerateStatement()
+	words := strings.Fields(stmt)
+	if len(words) < 3 {
+		return nil, ErrUnsupportedByMemoryExecutor
+	}
+	name := strings.ToLower(words[len(words)-1])
+
+	switch strings.ToUpper(words[0] + " " + words[1]) {
+	case "CREATE KEYSPACE", "ALTER KEYSPACE", "CREATE TABLE", "ALTER TABLE":
+		return func() error { return nil }, nil
+	case "DROP TABLE", "TRUNCATE TABLE":
+		return func() error {
+			delete(qe.tables, name)
+			return nil
+		}, nil
+	case "DROP KEYSPACE":
+		return func() error {
+			for k := range qe.tables {
+				if strings.HasPrefix(k, name+".") {
+					delete(qe.tables, k)
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	return nil, ErrUnsupportedByMemoryExecutor
+}
+
+// selectRows returns the live rows matching the query in clustering order
+func (qe *memoryExecutor) selectRows(q Query) ([]map[string]interface{}, error) {
+	qe.mtx.RLock()
+	defer qe.mtx.RUnlock()
+
+	for _, r := range q.relations {
+		if strings.HasPrefix(strings.ToLower(r.key), "token(") {
+			return nil, ErrUnsupportedByMemoryExecutor
+		}
+	}
+
+	t := qe.tables[memoryTableName(q.table)]
+	if t == nil {
+		return []map[string]interface{}{}, nil
+	}
+
+	orderings := q.orderings
+	if len(q.options.Orderings) > 0 {
+		orderings = q.options.Orderings
+	}
+	reverse := false
+	if len(orderings) > 0 {
+		column := strings.ToLower(orderings[0].Column)
+		reverse = orderings[0].Direction != t.direction(column)
+	}
+
+	limit := q.limit
+	if q.options.Limit > 0 {
+		limit = q.options.Limit
+	}
+
+	now := qe.now()
+	rows := []map[string]interface{}{}
+	for _, p := range t.sortedPartitions() {
+		partitionRows := []map[string]interface{}{}
+		for _, r := range p.rows {
+			row := r.toMap(t, p, now)
+			if row == nil {
+				continue
+			}
+			matched, err := matchRelations(row, q.relations)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				partitionRows = append(partitionRows, row)
+			}
+		}
+
+		if reverse {
+			for i, j := 0, len(partitionRows)-1; i < j; i, j = i+1, j-1 {
+				partitionRows[i], partitionRows[j] = partitionRows[j], partitionRows[i]
+			}
+		}
+		rows = append(rows, partitionRows...)
+	}
+
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	if len(q.selections) > 0 {
+		for i, row := range rows {
+			rows[i] = selectColumns(row, q.selections)
+		}
+	}
+
+	return rows, nil
+}
+
+// prepareWrite checks the query and returns a function which applies it, it
+// must be called while holding the write lock.
+func (qe *memoryExecutor) prepareWrite(q Query, now time.Time) (func() error, error) {
+	timestamp := now.UnixNano() / 1000
+	if !q.options.Timestamp.IsZero() {
+		timestamp = q.options.Timestamp.UnixNano() / 1000
+	}
+	var expires time.Time
+	if q.options.TTL > 0 {
+		expires = now.Add(q.options.TTL)
+	}
+
+	switch q.queryType {
+	case InsertQueryType:
+		pk, ck, err := insertKeys(q)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			t := qe.table(q.table)
+			r := t.partition(pk).row(t, ck)
+			if timestamp >= r.marker.timestamp {
+				r.marker = memoryCell{value: true, timestamp: timestamp, expires: expires}
+			}
+			return r.write(t, q.values, timestamp, expires, now)
+		}, nil
+	case UpdateQueryType:
+		pks, err := partitionKeys(q)
+		if err != nil {
+			return nil, err
+		}
+		cks, err := clusteringKeys(q)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			t := qe.table(q.table)
+			for _, pk := range pks {
+				p := t.partition(pk)
+				for _, ck := range cks {
+					if err := p.row(t, ck).write(t, q.values, timestamp, expires, now); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}, nil
+	case DeleteQueryType:
+		pks, err := partitionKeys(q)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			t := qe.table(q.table)
+			for _, pk := range pks {
+				p := t.partitions[memoryKey(pk)]
+				if p == nil {
+					continue
+				}
+				if err := p.delete(t, q, timestamp, now); err != nil {
+					return err
+				}
+				if len(p.rows) == 0 {
+					delete(t.partitions, memoryKey(pk))
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Cannot execute %s query", q.queryType)
+}
+
+func (qe *memoryExecutor) table(table *Table) *memoryTable {
+	name := memoryTableName(table)
+	t := qe.tables[name]
+	if t == nil {
+		t = &memoryTable{partitions: map[string]*memoryPartition{}}
+		qe.tables[name] = t
+	}
+	t.table = table
+
+	return t
+}
+
+func memoryTableName(table *Table) string {
+	return strings.ToLower(table.keyspace.Name() + "." + table.Name())
+}
+
+type memoryTable struct {
+	table      *Table
+	partitions map[string]*memoryPartition
+}
+
+func (t *memoryTable) partition(key []interface{}) *memoryPartition {
+	k := memoryKey(key)
+	p := t.partitions[k]
+	if p == nil {
+		p = &memoryPartition{key: key}
+		t.partitions[k] = p
+	}
+
+	return p
+}
+
+func (t *memoryTable) sortedPartitions() []*memoryPartition {
+	keys := make([]string, 0, len(t.partitions))
+	for k := range t.partitions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	partitions := make([]*memoryPartition, len(keys))
+	for i, k := range keys {
+		partitions[i] = t.partitions[k]
+	}
+
+	return partitions
+}
+
+// direction returns the clustering order of the column
+func (t *memoryTable) direction(column string) ColumnDirection {
+	for _, ordering := range t.table.options.Orderings {
+		if strings.ToLower(ordering.Column) == column {
+			return ordering.Direction
+		}
+	}
+
+	return ASC
+}
+
+// fieldType returns the Go type of the column in the table document, or nil
+// if the column is not part of the document
+func (t *memoryTable) fieldType(column string) reflect.Type {
+	for _, field := range t.table.documentFields {
+		if field.name == column {
+			return field.fieldType
+		}
+	}
+
+	return nil
+}
+
+// compareClustering compares two sets of clustering column values using the
+// clustering order of the table
+func (t *memoryTable) compareClustering(a, b []interface{}) int {
+	for i, column := range t.table.clusteringColumns {
+		c, _ := compareValues(a[i], b[i])
+		if c == 0 {
+			continue
+		}
+		if t.direction(column) == DESC {
+			return -c
+		}
+		return c
+	}
+
+	return 0
+}
+
+type memoryPartition struct {
+	key  []interface{}
+	rows []*memoryRow
+}
+
+// row returns the row with the given clustering values, inserting an empty
+// row in clustering order if it does not exist
+func (p *memoryPartition) row(t *memoryTable, clustering []interface{}) *memoryRow {
+	i := sort.Search(len(p.rows), func(i int) bool {
+		return t.compareClustering(p.rows[i].clustering, clustering) >= 0
+	})
+	if i < len(p.rows) && t.compareClustering(p.rows[i].clustering, clustering) == 0 {
+		return p.rows[i]
+	}
+
+	r := &memoryRow{clustering: clustering, cells: map[string]memoryCell{}}
+	p.rows = append(p.rows, nil)
+	copy(p.rows[i+1:], p.rows[i:])
+	p.rows[i] = r
+
+	return r
+}
+
+// delete removes the rows of the partition matching the query, or if the
+// query has selections only the selected columns or collection elements.
+// Cells written with a later timestamp than the delete are kept.
+func (p *memoryPartition) delete(t *memoryTable, q Query, timestamp int64, now time.Time) error {
+	rows := p.rows[:0]
+	for _, r := range p.rows {
+		row := r.keyMap(t, p)
+		matched, err := matchRelations(row, q.relations)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			rows = append(rows, r)
+			continue
+		}
+
+		if len(q.selections) == 0 {
+			if r.marker.timestamp <= timestamp {
+				r.marker = memoryCell{}
+			}
+			for column, cell := range r.cells {
+				if cell.timestamp <= timestamp {
+					delete(r.cells, column)
+				}
+			}
+		}
+		for _, s := range q.selections {
+			column := strings.ToLower(s.identifier)
+			cell, ok := r.cells[column]
+			if !ok || cell.timestamp > timestamp {
+				continue
+			}
+			if s.term == nil {
+				delete(r.cells, column)
+				continue
+			}
+
+			value, err := deleteElement(cell.live(now), s.term)
+			if err != nil {
+				return fmt.Errorf("Cannot delete %s: %v", s.generateCQL(), err)
+			}
+			r.cells[column] = memoryCell{value: nullIfEmpty(value), timestamp: timestamp, expires: cell.expires}
+		}
+
+		if r.marker.timestamp != 0 || len(r.cells) > 0 {
+			rows = append(rows, r)
+		}
+	}
+	p.rows = rows
+
+	return nil
+}
+
+type memoryRow struct {
+	clustering []interface{}
+	// marker is set by inserts so that the row exists even if all of its
+	// other columns are null
+	marker memoryCell
+	cells  map[string]memoryCell
+}
+
+type memoryCell struct {
+	value     interface{}
+	timestamp int64
+	expires   time.Time
+}
+
+// live returns the value of the cell or nil if it has expired
+func (c memoryCell) live(now time.Time) interface{} {
+	if !c.expires.IsZero() && !now.Before(c.expires) {
+		return nil
+	}
+
+	return c.value
+}
+
+// write sets the values of the row, applying any modifiers to the current
+// values. Values are ignored if the existing cell has a later timestamp.
+func (r *memoryRow) write(t *memoryTable, values map[string]interface{}, timestamp int64, expires, now time.Time) error {
+	keys := map[string]bool{}
+	for _, k := range append(append([]string{}, t.table.partitionKeys...), t.table.clusteringColumns...) {
+		keys[k] = true
+	}
+
+	for column, v := range values {
+		if keys[column] {
+			continue
+		}
+
+		cell := r.cells[column]
+		if cell.timestamp > timestamp {
+			continue
+		}
+
+		if mod, ok := v.(Modifier); ok {
+			var err error
+			v, err = applyModifier(cell.live(now), mod, t.fieldType(column))
+			if err != nil {
+				return fmt.Errorf("Cannot update %s: %v", column, err)
+			}
+		} else {
+			v = copyValue(v)
+		}
+
+		r.cells[column] = memoryCell{value: nullIfEmpty(v), timestamp: timestamp, expires: expires}
+	}
+
+	return nil
+}
+
+// keyMap returns the primary key columns of the row
+func (r *memoryRow) keyMap(t *memoryTable, p *memoryPartition) map[string]interface{} {
+	m := map[string]interface{}{}
+	for i, k := range t.table.partitionKeys {
+		m[k] = p.key[i]
+	}
+	for i, k := range t.table.clusteringColumns {
+		m[k] = r.clustering[i]
+	}
+
+	return m
+}
+
+// toMap returns a copy of the live columns of the row, or nil if the row has
+// no live columns
+func (r *memoryRow) toMap(t *memoryTable, p *memoryPartition, now time.Time) map[string]interface{} {
+	m := r.keyMap(t, p)
+	live := r.marker.timestamp != 0 && r.marker.live(now) != nil
+	for column, cell := range r.cells {
+		if v := cell.live(now); v != nil {
+			m[column] = copyValue(v)
+			live = true
+		}
+	}
+	if !live {
+		return nil
+	}
+
+	return m
+}
+
+// matchRelations returns true if the row satisfies every relation
+func matchRelations(row map[string]interface{}, relations []Relation) (bool, error) {
+	for _, r := range relations {
+		v, ok := row[strings.ToLower(r.key)]
+		if !ok || v == nil {
+			return false, nil
+		}
+
+		matched := false
+		for _, term := range r.terms {
+			c, err := compareValues(v, encoding.MarshalValue(term))
+			if err != nil {
+				return false, fmt.Errorf("Cannot compare %s: %v", r.key, err)
+			}
+
+			switch r.relationType {
+			case relationTypeEQ, relationTypeIN:
+				matched = c == 0
+			case relationTypeGT:
+				matched = c > 0
+			case relationTypeGE:
+				matched = c >= 0
+			case relationTypeLT:
+				matched = c < 0
+			case relationTypeLE:
+				matched = c <= 0
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// selectColumns returns the selected columns of the row, collection elements
+// are returned using the CQL of the selection as the column name
+func selectColumns(row map[string]interface{}, selections []Selection) map[string]interface{} {
+	m := make(map[string]interface{}, len(selections))
+	for _, s := range selections {
+		v := row[strings.ToLower(s.identifier)]
+		if s.term == nil {
+			m[strings.ToLower(s.identifier)] = v
+			continue
+		}
+
+		m[s.generateCQL()] = selectElement(v, s.term)
+	}
+
+	return m
+}
+
+func selectElement(v interface{}, term interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		key, err := convertValue(reflect.ValueOf(term), rv.Type().Key())
+		if err != nil {
+			return nil
+		}
+		if e := rv.MapIndex(key); e.IsValid() {
+			return e.Interface()
+		}
+	case reflect.Slice:
+		if i, ok := term.(int); ok && i >= 0 && i < rv.Len() {
+			return rv.Index(i).Interface()
+		}
+	}
+
+	return nil
+}
+
+func deleteElement(v interface{}, term interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		key, err := convertValue(reflect.ValueOf(term), rv.Type().Key())
+		if err != nil {
+			return nil, err
+		}
+		m := reflect.MakeMap(rv.Type())
+		for _, k := range rv.MapKeys() {
+			if k.Interface() != key.Interface() {
+				m.SetMapIndex(k, rv.MapIndex(k))
+			}
+		}
+		return m.Interface(), nil
+	case reflect.Slice:
+		i, ok := term.(int)
+		if !ok || i < 0 || i >= rv.Len() {
+			return nil, fmt.Errorf("List index %v out of bounds", term)
+		}
+		l := reflect.MakeSlice(rv.Type(), 0, rv.Len()-1)
+		l = reflect.AppendSlice(l, rv.Slice(0, i))
+		l = reflect.AppendSlice(l, rv.Slice(i+1, rv.Len()))
+		return l.Interface(), nil
+	case reflect.Invalid:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("%T is not a collection", v)
+}
+
+// applyModifier returns the result of applying the modifier to the current
+// value of a column, typ is the type of the column if known
+func applyModifier(current interface{}, mod Modifier, typ reflect.Type) (interface{}, error) {
+	mod = mod.marshalArgs()
+	cur := reflect.ValueOf(current)
+	if typ == nil && cur.IsValid() {
+		typ = cur.Type()
+	}
+
+	switch mod.op {
+	case modifierListPrepend, modifierListAppend, modifierListSetAtIndex, modifierListRemove:
+		value := mod.args[len(mod.args)-1]
+		if typ == nil {
+			typ = reflect.SliceOf(reflect.TypeOf(value))
+		}
+		if typ.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("%s is not a list", typ)
+		}
+		elem, err := convertValue(reflect.ValueOf(value), typ.Elem())
+		if err != nil {
+			return nil, err
+		}
+		// A list which has never been set is treated as empty
+		if !cur.IsValid() {
+			cur = reflect.MakeSlice(typ, 0, 0)
+		}
+
+		l := reflect.MakeSlice(typ, 0, cur.Len()+1)
+		switch mod.op {
+		case modifierListPrepend:
+			l = reflect.Append(l, elem)
+			l = reflect.AppendSlice(l, cur)
+		case modifierListAppend:
+			l = reflect.AppendSlice(l, cur)
+			l = reflect.Append(l, elem)
+		case modifierListSetAtIndex:
+			i := mod.args[0].(int)
+			if i < 0 || i >= cur.Len() {
+				return nil, fmt.Errorf("List index %d out of bounds", i)
+			}
+			l = reflect.AppendSlice(l, cur)
+			l.Index(i).Set(elem)
+		case modifierListRemove:
+			for i := 0; i < cur.Len(); i++ {
+				if c, err := compareValues(cur.Index(i).Interface(), elem.Interface()); err != nil || c != 0 {
+					l = reflect.Append(l, cur.Index(i))
+				}
+			}
+		}
+		return l.Interface(), nil
+	case modifierMapSetField, modifierMapSetFields:
+		fields := map[interface{}]interface{}{}
+		if mod.op == modifierMapSetField {
+			fields[mod.args[0]] = mod.args[1]
+		} else {
+			m, ok := mod.args[0].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("Argument for MapSetFields is not a map: %v", mod.args[0])
+			}
+			for k, v := range m {
+				fields[k] = v
+			}
+		}
+		if typ == nil {
+			for k, v := range fields {
+				typ = reflect.MapOf(reflect.TypeOf(k), reflect.TypeOf(v))
+				break
+			}
+		}
+		if typ == nil {
+			return current, nil
+		}
+		if typ.Kind() != reflect.Map {
+			return nil, fmt.Errorf("%s is not a map", typ)
+		}
+
+		m := reflect.MakeMap(typ)
+		if cur.IsValid() {
+			for _, k := range cur.MapKeys() {
+				m.SetMapIndex(k, cur.MapIndex(k))
+			}
+		}
+		for k, v := range fields {
+			key, err := convertValue(reflect.ValueOf(k), typ.Key())
+			if err != nil {
+				return nil, err
+			}
+			value, err := convertValue(reflect.ValueOf(v), typ.Elem())
+			if err != nil {
+				return nil, err
+			}
+			m.SetMapIndex(key, value)
+		}
+		return m.Interface(), nil
+	case modifierCounterIncrement:
+		var n int64
+		if current != nil {
+			v, ok := normalizeValue(current).(int64)
+			if !ok {
+				return nil, fmt.Errorf("%T is not a counter", current)
+			}
+			n = v
+		}
+		return n + int64(mod.args[0].(int)), nil
+	}
+
+	return nil, ErrUnsupportedByMemoryExecutor
+}
+
+// convertValue converts v to the type t, numbers may be converted between
+// types but other values must be assignable
+func convertValue(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	switch {
+	case !v.IsValid():
+		return reflect.Zero(t), nil
+	case v.Type().AssignableTo(t):
+		return v, nil
+	case isNumberKind(v.Kind()) && isNumberKind(t.Kind()),
+		v.Kind() == reflect.String && t.Kind() == reflect.String:
+		return v.Convert(t), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("Cannot use %s as %s", v.Type(), t)
+}
+
+// nullIfEmpty returns nil for empty lists and maps, which Cassandra stores as
+// null. Empty blobs are values in their own right so are kept.
+func nullIfEmpty(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		fallthrough
+	case reflect.Map:
+		if rv.Len() == 0 {
+			return nil
+		}
+	}
+
+	return v
+}
+
+// copyValue copies slices and maps so that stored values cannot be modified
+// by the caller
+func copyValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(c, rv)
+		return c.Interface()
+	case reflect.Map:
+		if rv.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		for _, k := range rv.MapKeys() {
+			c.SetMapIndex(k, rv.MapIndex(k))
+		}
+		return c.Interface()
+	}
+
+	return v
+}
+
+// normalizeValue converts numbers to int64 or float64 and strings to string
+// so that values of different Go types can be compared
+func normalizeValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	}
+
+	return v
+}
+
+// memoryKey encodes a partition key so it can be used as a map key
+func memoryKey(values []interface{}) string {
+	normalized := make([]interface{}, len(values))
+	for i, v := range values {
+		normalized[i] = normalizeValue(v)
+	}
+
+	return fmt.Sprintf("%#v", normalized)
+}
+
+// compareValues returns -1, 0 or 1 if a is less than, equal to or greater
+// than b, using the ordering Cassandra uses for the type
+func compareValues(a, b interface{}) (int, error) {
+	a, b = normalizeValue(a), normalizeValue(b)
+
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			return compareOrdered(a < b, a > b), nil
+		case float64:
+			return compareOrdered(float64(a) < b, float64(a) > b), nil
+		}
+	case float64:
+		switch b := b.(type) {
+		case int64:
+			return compareOrdered(a < float64(b), a > float64(b)), nil
+		case float64:
+			return compareOrdered(a < b, a > b), nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			return compareOrdered(!a && b, a && !b), nil
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return compareOrdered(a.Before(b), a.After(b)), nil
+		}
+	case []byte:
+		if b, ok := b.([]byte); ok {
+			return bytes.Compare(a, b), nil
+		}
+	case gocql.UUID:
+		if b, ok := b.(gocql.UUID); ok {
+			// Time based UUIDs are ordered by their time
+			if a.Version() == 1 && b.Version() == 1 && !a.Time().Equal(b.Time()) {
+				return compareOrdered(a.Time().Before(b.Time()), a.Time().After(b.Time())), nil
+			}
+			return bytes.Compare(a[:], b[:]), nil
+		}
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("Cannot compare %T with %T", a, b)
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// rowsIter iterates over rows which have already been read, if a page size
+// was set then NumRows and PageState describe the first page so that
+// RunnableQuery.Page behaves as it would with Cassandra.
+type rowsIter struct {
+	rows      []map[string]interface{}
+	pos       int
+	numRows   int
+	pageState []byte
+	err       error
+}
+
+func newRowsIter(rows []map[string]interface{}, options QueryOptions) *rowsIter {
+	iter := &rowsIter{rows: rows}
+	if len(options.PageState) > 0 {
+		offset, err := strconv.Atoi(string(options.PageState))
+		if err != nil || offset < 0 || offset > len(rows) {
+			return &rowsIter{err: fmt.Errorf("Invalid page state %q", options.PageState)}
+		}
+		iter.rows = rows[offset:]
+	}
+
+	iter.numRows = len(iter.rows)
+	if options.PageSize > 0 && options.PageSize < len(iter.rows) {
+		iter.numRows = options.PageSize
+		iter.pageState = []byte(strconv.Itoa(len(rows) - len(iter.rows) + options.PageSize))
+	}
+
+	return iter
+}
+
+func (iter *rowsIter) Scan(dest interface{}) bool {
+	if iter.err != nil || iter.pos >= len(iter.rows) {
+		return false
+	}
+
+	row := iter.rows[iter.pos]
+	iter.pos++
+
+	if err := decodeRow(row, dest); err != nil {
+		iter.err = err
+
+		return false
+	}
+
+	return true
+}
+
+func (iter *rowsIter) NumRows() int {
+	return iter.numRows
+}
+
+func (iter *rowsIter) WillSwitchPage() bool {
+	return false
+}
+
+func (iter *rowsIter) PageState() []byte {
+	return iter.pageState
+}
+
+func (iter *rowsIter) GetCustomPayload() map[string][]byte {
+	return nil
+}
+
+func (iter *rowsIter) Close() error {
+	return iter.err
+}
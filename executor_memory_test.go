@@ -0,0 +1,214 @@
+package gocassa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryEvent struct {
+	UserID  string
+	Created int
+	Name    string
+}
+
+type memoryDocument struct {
+	ID     string
+	List   []string
+	Map    map[string]int
+	Amount int
+}
+
+func TestMemoryExecutor_clusteringOrder(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewTable(k, "events", memoryEvent{}, []string{"userid"}, []string{"created"}, &TableOptions{
+		Orderings: []Ordering{{"created", DESC}},
+	})
+
+	for _, e := range []memoryEvent{
+		{"a", 2, "two"},
+		{"a", 3, "three"},
+		{"a", 1, "one"},
+		{"b", 1, "other"},
+	} {
+		assert.Nil(t, tbl.Set(e).Execute())
+	}
+
+	events := []memoryEvent{}
+	assert.Nil(t, tbl.Where(Eq("userid", "a")).Read().Scan(&events))
+	assert.Equal(t, []memoryEvent{{"a", 3, "three"}, {"a", 2, "two"}, {"a", 1, "one"}}, events)
+
+	events = []memoryEvent{}
+	assert.Nil(t, tbl.Where(Eq("userid", "a"), GT("created", 1)).Read().Scan(&events))
+	assert.Equal(t, []memoryEvent{{"a", 3, "three"}, {"a", 2, "two"}}, events)
+
+	events = []memoryEvent{}
+	q := NewQuery(tbl, SelectQueryType).Where(Eq("userid", "a")).OrderBy(Ordering{"created", ASC}).Limit(2)
+	assert.Nil(t, RunnableQuery{Executor: k.QueryExecutor(), Query: q}.Scan(&events))
+	assert.Equal(t, []memoryEvent{{"a", 1, "one"}, {"a", 2, "two"}}, events)
+
+	events = []memoryEvent{}
+	assert.Nil(t, tbl.List().Scan(&events))
+	assert.Len(t, events, 4)
+}
+
+func TestMemoryExecutor_mapTable(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id")
+
+	assert.Nil(t, tbl.Set(memoryDocument{ID: "1", List: []string{"a"}, Map: map[string]int{"a": 1}}).Execute())
+	assert.Nil(t, tbl.Update("1", map[string]interface{}{
+		"list": ListAppend("b"),
+		"map":  MapSetField("b", 2),
+	}).Execute())
+	assert.Nil(t, tbl.Update("1", map[string]interface{}{
+		"list": ListPrepend("z"),
+	}).Execute())
+
+	doc := memoryDocument{}
+	assert.Nil(t, tbl.Read("1").ScanOne(&doc))
+	assert.Equal(t, memoryDocument{ID: "1", List: []string{"z", "a", "b"}, Map: map[string]int{"a": 1, "b": 2}}, doc)
+
+	assert.Nil(t, tbl.Where(Eq("id", "1")).Delete(MapKey("map", "a"), ListIndex("list", 0)).Execute())
+	doc = memoryDocument{}
+	assert.Nil(t, tbl.Read("1").ScanOne(&doc))
+	assert.Equal(t, []string{"a", "b"}, doc.List)
+	assert.Equal(t, map[string]int{"b": 2}, doc.Map)
+
+	assert.Nil(t, tbl.Delete("1").Execute())
+	assert.NotNil(t, tbl.Read("1").ScanOne(&doc))
+}
+
+func TestMemoryExecutor_unsetList(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id")
+
+	doc := memoryDocument{}
+	assert.Nil(t, tbl.Update("1", map[string]interface{}{"list": ListAppend("a")}).Execute())
+	assert.Nil(t, tbl.Read("1").ScanOne(&doc))
+	assert.Equal(t, []string{"a"}, doc.List)
+
+	doc = memoryDocument{}
+	assert.Nil(t, tbl.Update("2", map[string]interface{}{"list": ListPrepend("a")}).Execute())
+	assert.Nil(t, tbl.Read("2").ScanOne(&doc))
+	assert.Equal(t, []string{"a"}, doc.List)
+
+	// An empty list is null so removing from an unset list creates no row
+	assert.Nil(t, tbl.Update("3", map[string]interface{}{"list": ListRemove("a")}).Execute())
+	assert.Equal(t, gocql.ErrNotFound, tbl.Read("3").ScanOne(&doc))
+
+	// Removing the last element leaves no live columns either
+	assert.Nil(t, tbl.Update("1", map[string]interface{}{"list": ListRemove("a")}).Execute())
+	assert.Equal(t, gocql.ErrNotFound, tbl.Read("1").ScanOne(&doc))
+
+	err := tbl.Update("4", map[string]interface{}{"list": ListSetAtIndex(0, "a")}).Execute()
+	assert.NotNil(t, err, "setting an index of an unset list is out of bounds")
+}
+
+func TestMemoryExecutor_emptyIn(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id")
+	assert.Nil(t, tbl.Set(memoryDocument{ID: "1"}).Execute())
+
+	docs := []memoryDocument{}
+	assert.Nil(t, tbl.MultiRead([]interface{}{}).Scan(&docs))
+	assert.Empty(t, docs)
+
+	docs = []memoryDocument{}
+	assert.Nil(t, tbl.Where(In("amount")).Read().WithOptions(QueryOptions{AllowFiltering: true}).Scan(&docs))
+	assert.Empty(t, docs)
+}
+
+func TestMemoryExecutor_counter(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id")
+
+	assert.Nil(t, tbl.Update("1", map[string]interface{}{"amount": CounterIncrement(5)}).Execute())
+	assert.Nil(t, tbl.Update("1", map[string]interface{}{"amount": CounterIncrement(-2)}).Execute())
+
+	doc := memoryDocument{}
+	assert.Nil(t, tbl.Read("1").ScanOne(&doc))
+	assert.Equal(t, 3, doc.Amount)
+}
+
+func TestMemoryExecutor_ttlAndTimestamp(t *testing.T) {
+	qe := NewMemoryExecutor()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	qe.(*memoryExecutor).now = func() time.Time { return now }
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id")
+
+	assert.Nil(t, tbl.Insert(map[string]interface{}{"id": "1", "amount": 1}).WithOptions(QueryOptions{
+		TTL: time.Minute,
+	}).Execute())
+	assert.Nil(t, tbl.Update("1", map[string]interface{}{"amount": 2}).WithOptions(QueryOptions{
+		Timestamp: now.Add(-time.Hour),
+	}).Execute())
+
+	doc := memoryDocument{}
+	assert.Nil(t, tbl.Read("1").ScanOne(&doc))
+	assert.Equal(t, 1, doc.Amount, "older writes are ignored")
+
+	now = now.Add(time.Minute)
+	assert.NotNil(t, tbl.Read("1").ScanOne(&doc))
+}
+
+func TestMemoryExecutor_batch(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewTable(k, "events", memoryEvent{}, []string{"userid"}, []string{"created"}, nil)
+
+	err := MultiQuery(
+		tbl.Set(memoryEvent{"a", 1, "one"}),
+		tbl.Where(Eq("userid", "a")).Update(map[string]interface{}{"name": "missing clustering column"}),
+	).ExecuteBatch()
+	assert.NotNil(t, err)
+
+	events := []memoryEvent{}
+	assert.Nil(t, tbl.List().Scan(&events))
+	assert.Empty(t, events, "batches are applied atomically")
+
+	assert.Nil(t, MultiQuery(
+		tbl.Set(memoryEvent{"a", 1, "one"}),
+		tbl.Set(memoryEvent{"a", 2, "two"}),
+	).ExecuteBatch())
+	assert.Nil(t, tbl.List().Scan(&events))
+	assert.Len(t, events, 2)
+
+	assert.Nil(t, tbl.Truncate())
+	events = []memoryEvent{}
+	assert.Nil(t, tbl.List().Scan(&events))
+	assert.Empty(t, events)
+}
+
+func TestMemoryExecutor_page(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewTable(k, "events", memoryEvent{}, []string{"userid"}, []string{"created"}, nil)
+	assert.Nil(t, tbl.Recreate())
+
+	for i := 1; i <= 3; i++ {
+		assert.Nil(t, tbl.Set(memoryEvent{"a", i, ""}).Execute())
+	}
+
+	query := tbl.List().WithOptions(QueryOptions{PageSize: 2})
+	events := []memoryEvent{}
+	pageState, err := query.Page(nil, &events)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+
+	events = []memoryEvent{}
+	pageState, err = query.Page(pageState, &events)
+	assert.Nil(t, err)
+	assert.Nil(t, pageState)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, 3, events[0].Created)
+	}
+}
+
+func TestMemoryExecutor_raw(t *testing.T) {
+	qe := NewMemoryExecutor()
+	_, err := qe.Query(NewRawQuery("SELECT * FROM test.events", nil))
+	assert.Equal(t, ErrUnsupportedByMemoryExecutor, err)
+}
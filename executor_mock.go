@@ -1,7 +1,7 @@
 package gocassa
 
 import (
-	"sync"
+	"context"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -24,18 +24,26 @@ import (
 // When mocking the Iter function pass the results you wish to iterate over as
 // a slice of maps ([]map[string]interface{}) and the executor will create the
 // iterator for you.
-func NewMockExecutor(m mock.Mock) QueryExecutor {
+//
+// The context aware functions (QueryContext, ExecuteContext etc) are recorded
+// under the name of the equivalent function without a context, so the same
+// expectations apply to both.
+func NewMockExecutor(m *mock.Mock) QueryExecutor {
 	return mockExecutor{
 		mock: m,
 	}
 }
 
 type mockExecutor struct {
-	mock mock.Mock
+	mock *mock.Mock
 }
 
 func (qe mockExecutor) QueryOne(query QueryGenerator) (map[string]interface{}, error) {
-	ret := qe.mock.Called(query.GenerateStatement())
+	return qe.QueryOneContext(context.Background(), query)
+}
+
+func (qe mockExecutor) QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error) {
+	ret := qe.mock.MethodCalled("QueryOne", generateStatementArgs(query)...)
 
 	return ret.Get(0).(map[string]interface{}), ret.Error(1)
 }
@@ -47,27 +55,43 @@ func (qe mockExecutor) QueryCAS(query QueryGenerator) (result map[string]interfa
 }
 
 func (qe mockExecutor) Query(query QueryGenerator) ([]map[string]interface{}, error) {
-	ret := qe.mock.Called(query.GenerateStatement())
+	return qe.QueryContext(context.Background(), query)
+}
+
+func (qe mockExecutor) QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error) {
+	ret := qe.mock.MethodCalled("Query", generateStatementArgs(query)...)
 
 	return ret.Get(0).([]map[string]interface{}), ret.Error(1)
 }
 
 func (qe mockExecutor) Iter(query QueryGenerator) Iter {
-	ret := qe.mock.Called(query.GenerateStatement())
+	return qe.IterContext(context.Background(), query)
+}
 
-	return mockIter{
+func (qe mockExecutor) IterContext(ctx context.Context, query QueryGenerator) Iter {
+	ret := qe.mock.MethodCalled("Iter", generateStatementArgs(query)...)
+
+	return &mockIter{
 		rows: ret.Get(0).([]map[string]interface{}),
 		err:  ret.Error(1),
 	}
 }
 
 func (qe mockExecutor) Execute(query QueryGenerator) error {
-	ret := qe.mock.Called(query.GenerateStatement())
+	return qe.ExecuteContext(context.Background(), query)
+}
+
+func (qe mockExecutor) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	ret := qe.mock.MethodCalled("Execute", generateStatementArgs(query)...)
 
 	return ret.Error(0)
 }
 
 func (qe mockExecutor) ExecuteBatch(queries []QueryGenerator, options QueryOptions) error {
+	return qe.ExecuteBatchContext(context.Background(), queries, options)
+}
+
+func (qe mockExecutor) ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error {
 	stmts := []string{}
 	values := [][]interface{}{}
 	for _, query := range queries {
@@ -76,7 +100,7 @@ func (qe mockExecutor) ExecuteBatch(queries []QueryGenerator, options QueryOptio
 		values = append(values, vals)
 	}
 
-	ret := qe.mock.Called(stmts, values)
+	ret := qe.mock.MethodCalled("ExecuteBatch", stmts, values)
 
 	return ret.Error(0)
 }
@@ -93,7 +117,7 @@ func (qe mockExecutor) ExecuteBatchCAS(queries []QueryGenerator, options QueryOp
 	ret := qe.mock.Called(stmts, values)
 	rows := ret.Get(0).([]map[string]interface{})
 
-	return rows[0], mockIter{
+	return rows[0], &mockIter{
 		rows: rows[1:],
 	}, ret.Bool(1), ret.Error(2)
 }
@@ -103,21 +127,27 @@ func (qe mockExecutor) Close() {
 	qe.mock.Called()
 }
 
+func generateStatementArgs(query QueryGenerator) []interface{} {
+	stmt, vals := query.GenerateStatement()
+	return []interface{}{stmt, vals}
+}
+
+// mockIter iterates over the mocked rows, decoding one row per call to Scan
 type mockIter struct {
-	mtx  sync.Mutex
 	rows []map[string]interface{}
+	pos  int
 	err  error
 }
 
-func (iter mockIter) Scan(dest interface{}) bool {
-	iter.mtx.Lock()
-	defer iter.mtx.Unlock()
-
-	if iter.err != nil {
+func (iter *mockIter) Scan(dest interface{}) bool {
+	if iter.err != nil || iter.pos >= len(iter.rows) {
 		return false
 	}
 
-	if err := decodeResult(iter.rows, dest); err != nil {
+	row := iter.rows[iter.pos]
+	iter.pos++
+
+	if err := decodeRow(row, dest); err != nil {
 		iter.err = err
 
 		return false
@@ -126,24 +156,22 @@ func (iter mockIter) Scan(dest interface{}) bool {
 	return true
 }
 
-func (iter mockIter) NumRows() int {
-	iter.mtx.Lock()
-	defer iter.mtx.Unlock()
-
+func (iter *mockIter) NumRows() int {
 	return len(iter.rows)
 }
 
-func (iter mockIter) WillSwitchPage() bool {
+func (iter *mockIter) WillSwitchPage() bool {
 	return false
 }
 
-func (iter mockIter) GetCustomPayload() map[string][]byte {
+func (iter *mockIter) PageState() []byte {
 	return nil
 }
 
-func (iter mockIter) Close() error {
-	iter.mtx.Lock()
-	defer iter.mtx.Unlock()
+func (iter *mockIter) GetCustomPayload() map[string][]byte {
+	return nil
+}
 
+func (iter *mockIter) Close() error {
 	return iter.err
 }
@@ -0,0 +1,859 @@
+package gocassa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
+)
+
+// A recording is the fixture file written by RecordingExecutor and read by
+// ReplayExecutor
+type recording struct {
+	Calls []recordedCall `json:"calls"`
+}
+
+// recordedCall is a single call to a query executor. Calls to the context
+// aware methods are recorded under the name of the method without a context.
+type recordedCall struct {
+	Method     string                     `json:"method"`
+	Statements []recordedStatement        `json:"statements"`
+	Rows       []map[string]recordedValue `json:"rows,omitempty"`
+	NumRows    int                        `json:"num_rows,omitempty"`
+	PageState  []byte                     `json:"page_state,omitempty"`
+	Applied    bool                       `json:"applied,omitempty"`
+	Error      string                     `json:"error,omitempty"`
+}
+
+type recordedStatement struct {
+	Statement string          `json:"statement"`
+	Values    []recordedValue `json:"values,omitempty"`
+}
+
+// A recordedValue stores the CQL-like type of a value alongside its JSON
+// encoding so that it is decoded to the same Go type when replayed
+type recordedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// RecordingExecutor wraps a query executor and records every statement, its
+// bound values and the results so that they can be replayed by
+// ReplayExecutor. The recording is written to the fixture file by Save.
+type RecordingExecutor struct {
+	Next QueryExecutor
+
+	path string
+	mtx  sync.Mutex
+	rec  recording
+	err  error
+}
+
+// NewRecordingExecutor creates a recording executor which executes queries
+// using next and saves the recording to the file at path
+func NewRecordingExecutor(next QueryExecutor, path string) *RecordingExecutor {
+	return &RecordingExecutor{
+		Next: next,
+		path: path,
+	}
+}
+
+// Save writes the recorded calls to the fixture file. An error is returned if
+// any of the values could not be recorded.
+func (qe *RecordingExecutor) Save() error {
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	if qe.err != nil {
+		return qe.err
+	}
+
+	data, err := json.MarshalIndent(qe.rec, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(qe.path, append(data, '\n'), 0644)
+}
+
+func (qe *RecordingExecutor) record(method string, queries []QueryGenerator, rows []map[string]interface{}, call recordedCall, err error) {
+	call.Method = method
+	if err != nil {
+		call.Error = err.Error()
+	}
+
+	var encodeErr error
+	call.Statements, encodeErr = encodeStatements(queries)
+	if encodeErr == nil {
+		for _, row := range rows {
+			var r map[string]recordedValue
+			if r, encodeErr = encodeRow(row); encodeErr != nil {
+				break
+			}
+			call.Rows = append(call.Rows, r)
+		}
+	}
+
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	if encodeErr != nil && qe.err == nil {
+		qe.err = encodeErr
+	}
+	qe.rec.Calls = append(qe.rec.Calls, call)
+}
+
+func (qe *RecordingExecutor) QueryOne(query QueryGenerator) (map[string]interface{}, error) {
+	return qe.QueryOneContext(context.Background(), query)
+}
+
+func (qe *RecordingExecutor) QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error) {
+	row, err := qe.Next.QueryOneContext(ctx, query)
+	qe.record("QueryOne", []QueryGenerator{query}, oneRow(row), recordedCall{}, err)
+
+	return row, err
+}
+
+func (qe *RecordingExecutor) QueryCAS(query QueryGenerator) (result map[string]interface{}, applied bool, err error) {
+	result, applied, err = qe.Next.QueryCAS(query)
+	qe.record("QueryCAS", []QueryGenerator{query}, oneRow(result), recordedCall{Applied: applied}, err)
+
+	return result, applied, err
+}
+
+func (qe *RecordingExecutor) Query(query QueryGenerator) ([]map[string]interface{}, error) {
+	return qe.QueryContext(context.Background(), query)
+}
+
+func (qe *RecordingExecutor) QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error) {
+	rows, err := qe.Next.QueryContext(ctx, query)
+	qe.record("Query", []QueryGenerator{query}, rows, recordedCall{}, err)
+
+	return rows, err
+}
+
+func (qe *RecordingExecutor) Iter(query QueryGenerator) Iter {
+	return qe.IterContext(context.Background(), query)
+}
+
+// IterContext returns an iterator which records the rows as they are scanned,
+// the call is recorded once the iterator is closed
+func (qe *RecordingExecutor) IterContext(ctx context.Context, query QueryGenerator) Iter {
+	iter := qe.Next.IterContext(ctx, query)
+
+	return &recordingIter{
+		Iter:  iter,
+		qe:    qe,
+		query: query,
+		call: recordedCall{
+			NumRows:   iter.NumRows(),
+			PageState: iter.PageState(),
+		},
+	}
+}
+
+func (qe *RecordingExecutor) Execute(query QueryGenerator) error {
+	return qe.ExecuteContext(context.Background(), query)
+}
+
+func (qe *RecordingExecutor) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	err := qe.Next.ExecuteContext(ctx, query)
+	qe.record("Execute", []QueryGenerator{query}, nil, recordedCall{}, err)
+
+	return err
+}
+
+func (qe *RecordingExecutor) ExecuteBatch(queries []QueryGenerator, options QueryOptions) error {
+	return qe.ExecuteBatchContext(context.Background(), queries, options)
+}
+
+func (qe *RecordingExecutor) ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error {
+	err := qe.Next.ExecuteBatchContext(ctx, queries, options)
+	qe.record("ExecuteBatch", queries, nil, recordedCall{}, err)
+
+	return err
+}
+
+// ExecuteBatchCAS records the initial result of the batch, rows returned by
+// the iterator are not recorded.
+func (qe *RecordingExecutor) ExecuteBatchCAS(queries []QueryGenerator, options QueryOptions) (result map[string]interface{}, iter Iter, applied bool, err error) {
+	result, iter, applied, err = qe.Next.ExecuteBatchCAS(queries, options)
+	qe.record("ExecuteBatchCAS", queries, oneRow(result), recordedCall{Applied: applied}, err)
+
+	return result, iter, applied, err
+}
+
+func (qe *RecordingExecutor) Close() {
+	qe.Next.Close()
+}
+
+// recordingIter records the rows scanned from the wrapped iterator
+type recordingIter struct {
+	Iter
+
+	qe     *RecordingExecutor
+	query  QueryGenerator
+	call   recordedCall
+	rows   []map[string]interface{}
+	err    error
+	closed bool
+}
+
+func (iter *recordingIter) Scan(dest interface{}) bool {
+	if iter.err != nil {
+		return false
+	}
+
+	var row map[string]interface{}
+	if !iter.Iter.Scan(&row) {
+		return false
+	}
+	iter.rows = append(iter.rows, row)
+
+	if err := decodeRow(row, dest); err != nil {
+		iter.err = err
+		return false
+	}
+
+	return true
+}
+
+func (iter *recordingIter) Close() error {
+	err := iter.Iter.Close()
+	if !iter.closed {
+		iter.closed = true
+		iter.qe.record("Iter", []QueryGenerator{iter.query}, iter.rows, iter.call, err)
+	}
+	if err == nil {
+		err = iter.err
+	}
+
+	return err
+}
+
+// ReplayExecutor serves the results recorded by RecordingExecutor. Each
+// recorded call is replayed once, for a call with the same method, statements
+// and values, regardless of the order the calls are made in. Calls which were
+// not recorded return an error and are reported by AssertExpectations.
+type ReplayExecutor struct {
+	mtx        sync.Mutex
+	calls      []recordedCall
+	keys       []string
+	used       []bool
+	unexpected []string
+}
+
+// NewReplayExecutor creates a replay executor from the fixture file at path
+func NewReplayExecutor(path string) (*ReplayExecutor, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := recording{}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("Cannot read recording %s: %v", path, err)
+	}
+
+	qe := &ReplayExecutor{
+		calls: rec.Calls,
+		keys:  make([]string, len(rec.Calls)),
+		used:  make([]bool, len(rec.Calls)),
+	}
+	for i, call := range rec.Calls {
+		qe.keys[i], err = recordedCallKey(call.Method, call.Statements)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return qe, nil
+}
+
+// AssertExpectations reports every recorded call which was not replayed and
+// every call which was not recorded. It returns false if any errors were
+// reported.
+func (qe *ReplayExecutor) AssertExpectations(t TestingT) bool {
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	ok := true
+	for i, call := range qe.calls {
+		if !qe.used[i] {
+			t.Errorf("Recorded %s was not replayed: %s", call.Method, describeStatements(call.Statements))
+			ok = false
+		}
+	}
+	for _, desc := range qe.unexpected {
+		t.Errorf("Unexpected %s", desc)
+		ok = false
+	}
+
+	return ok
+}
+
+// replay finds the first unused call matching the method and queries and
+// decodes its results
+func (qe *ReplayExecutor) replay(method string, queries []QueryGenerator) (recordedCall, []map[string]interface{}, error) {
+	statements, err := encodeStatements(queries)
+	if err != nil {
+		return recordedCall{}, nil, err
+	}
+	key, err := recordedCallKey(method, statements)
+	if err != nil {
+		return recordedCall{}, nil, err
+	}
+
+	qe.mtx.Lock()
+	defer qe.mtx.Unlock()
+
+	for i, k := range qe.keys {
+		if qe.used[i] || k != key {
+			continue
+		}
+		qe.used[i] = true
+
+		call := qe.calls[i]
+		rows := make([]map[string]interface{}, 0, len(call.Rows))
+		for _, r := range call.Rows {
+			row, err := decodeRecordedRow(r)
+			if err != nil {
+				return call, nil, err
+			}
+			rows = append(rows, row)
+		}
+
+		return call, rows, recordedError(call.Error)
+	}
+
+	desc := fmt.Sprintf("%s: %s", method, describeStatements(statements))
+	qe.unexpected = append(qe.unexpected, desc)
+
+	return recordedCall{}, nil, fmt.Errorf("Unexpected %s", desc)
+}
+
+func (qe *ReplayExecutor) QueryOne(query QueryGenerator) (map[string]interface{}, error) {
+	return qe.QueryOneContext(context.Background(), query)
+}
+
+func (qe *ReplayExecutor) QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error) {
+	_, rows, err := qe.replay("QueryOne", []QueryGenerator{query})
+	if err != nil {
+		return nil, err
+	}
+
+	return firstRow(rows), nil
+}
+
+func (qe *ReplayExecutor) QueryCAS(query QueryGenerator) (result map[string]interface{}, applied bool, err error) {
+	call, rows, err := qe.replay("QueryCAS", []QueryGenerator{query})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return firstRow(rows), call.Applied, nil
+}
+
+func (qe *ReplayExecutor) Query(query QueryGenerator) ([]map[string]interface{}, error) {
+	return qe.QueryContext(context.Background(), query)
+}
+
+func (qe *ReplayExecutor) QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error) {
+	_, rows, err := qe.replay("Query", []QueryGenerator{query})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (qe *ReplayExecutor) Iter(query QueryGenerator) Iter {
+	return qe.IterContext(context.Background(), query)
+}
+
+func (qe *ReplayExecutor) IterContext(ctx context.Context, query QueryGenerator) Iter {
+	call, rows, err := qe.replay("Iter", []QueryGenerator{query})
+
+	return &rowsIter{rows: rows, numRows: call.NumRows, pageState: call.PageState, err: err}
+}
+
+func (qe *ReplayExecutor) Execute(query QueryGenerator) error {
+	return qe.ExecuteContext(context.Background(), query)
+}
+
+func (qe *ReplayExecutor) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	_, _, err := qe.replay("Execute", []QueryGenerator{query})
+	return err
+}
+
+func (qe *ReplayExecutor) ExecuteBatch(queries []QueryGenerator, options QueryOptions) error {
+	return qe.ExecuteBatchContext(context.Background(), queries, options)
+}
+
+func (qe *ReplayExecutor) ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error {
+	_, _, err := qe.replay("ExecuteBatch", queries)
+	return err
+}
+
+func (qe *ReplayExecutor) ExecuteBatchCAS(queries []QueryGenerator, options QueryOptions) (result map[string]interface{}, iter Iter, applied bool, err error) {
+	call, rows, err := qe.replay("ExecuteBatchCAS", queries)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return firstRow(rows), &rowsIter{}, call.Applied, nil
+}
+
+func (qe *ReplayExecutor) Close() {}
+
+func oneRow(row map[string]interface{}) []map[string]interface{} {
+	if row == nil {
+		return nil
+	}
+
+	return []map[string]interface{}{row}
+}
+
+func firstRow(rows []map[string]interface{}) map[string]interface{} {
+	if len(rows) == 0 {
+		return map[string]interface{}{}
+	}
+
+	return rows[0]
+}
+
+// recordedError recreates a recorded error, gocql.ErrNotFound is returned as
+// itself so that callers can still compare against it
+func recordedError(msg string) error {
+	switch msg {
+	case "":
+		return nil
+	case gocql.ErrNotFound.Error():
+		return gocql.ErrNotFound
+	default:
+		return fmt.Errorf("%s", msg)
+	}
+}
+
+func recordedCallKey(method string, statements []recordedStatement) (string, error) {
+	data, err := json.Marshal(statements)
+	if err != nil {
+		return "", err
+	}
+
+	return method + " " + string(data), nil
+}
+
+func describeStatements(statements []recordedStatement) string {
+	desc := make([]string, len(statements))
+	for i, s := range statements {
+		desc[i] = s.Statement
+	}
+
+	return strings.Join(desc, "; ")
+}
+
+func encodeStatements(queries []QueryGenerator) ([]recordedStatement, error) {
+	statements := make([]recordedStatement, len(queries))
+	for i, query := range queries {
+		stmt, values := query.GenerateStatement()
+		statements[i].Statement = stmt
+		for _, v := range values {
+			rv, err := encodeRecordedValue(v)
+			if err != nil {
+				return nil, err
+			}
+			statements[i].Values = append(statements[i].Values, rv)
+		}
+	}
+
+	return statements, nil
+}
+
+func encodeRow(row map[string]interface{}) (map[string]recordedValue, error) {
+	r := make(map[string]recordedValue, len(row))
+	for k, v := range row {
+		rv, err := encodeRecordedValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot record column %s: %v", k, err)
+		}
+		r[k] = rv
+	}
+
+	return r, nil
+}
+
+func decodeRecordedRow(r map[string]recordedValue) (map[string]interface{}, error) {
+	row := make(map[string]interface{}, len(r))
+	for k, rv := range r {
+		v, err := decodeRecordedValue(rv)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot replay column %s: %v", k, err)
+		}
+		row[k] = v
+	}
+
+	return row, nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	uuidType     = reflect.TypeOf(gocql.UUID{})
+	blobType     = reflect.TypeOf([]byte{})
+	varintType   = reflect.TypeOf(&big.Int{})
+	decimalType  = reflect.TypeOf(&inf.Dec{})
+	durationType = reflect.TypeOf(gocql.Duration{})
+	inetType     = reflect.TypeOf(net.IP{})
+)
+
+var recordedTypes = map[string]reflect.Type{
+	"string":    reflect.TypeOf(""),
+	"bool":      reflect.TypeOf(false),
+	"int":       reflect.TypeOf(int(0)),
+	"int8":      reflect.TypeOf(int8(0)),
+	"int16":     reflect.TypeOf(int16(0)),
+	"int32":     reflect.TypeOf(int32(0)),
+	"int64":     reflect.TypeOf(int64(0)),
+	"uint":      reflect.TypeOf(uint(0)),
+	"uint8":     reflect.TypeOf(uint8(0)),
+	"uint16":    reflect.TypeOf(uint16(0)),
+	"uint32":    reflect.TypeOf(uint32(0)),
+	"uint64":    reflect.TypeOf(uint64(0)),
+	"float32":   reflect.TypeOf(float32(0)),
+	"float64":   reflect.TypeOf(float64(0)),
+	"timestamp": timeType,
+	"uuid":      uuidType,
+	"blob":      blobType,
+	"varint":    varintType,
+	"decimal":   decimalType,
+	"duration":  durationType,
+	"inet":      inetType,
+}
+
+// recordedValuesType is the type of a []interface{}, such as the terms of an
+// IN relation, whose elements are each recorded with their own type
+const recordedValuesType = "values"
+
+func encodeRecordedValue(v interface{}) (recordedValue, error) {
+	if v == nil {
+		return recordedValue{Type: "null"}, nil
+	}
+	if l, ok := v.([]interface{}); ok {
+		elems := make([]recordedValue, len(l))
+		for i, elem := range l {
+			rv, err := encodeRecordedValue(elem)
+			if err != nil {
+				return recordedValue{}, err
+			}
+			elems[i] = rv
+		}
+		data, err := json.Marshal(elems)
+		if err != nil {
+			return recordedValue{}, err
+		}
+		return recordedValue{Type: recordedValuesType, Value: data}, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		// Nil pointers, such as those created by encoding.MarshalValue for
+		// empty fields, are null. Other pointers are recorded as the value
+		// they point to.
+		if rv.IsNil() {
+			return recordedValue{Type: "null"}, nil
+		}
+		if rv.Type() != varintType && rv.Type() != decimalType {
+			return encodeRecordedValue(rv.Elem().Interface())
+		}
+	}
+	typ, err := recordedTypeName(rv.Type())
+	if err != nil {
+		return recordedValue{}, err
+	}
+	data, err := json.Marshal(encodeJSONValue(rv))
+	if err != nil {
+		return recordedValue{}, err
+	}
+
+	return recordedValue{Type: typ, Value: data}, nil
+}
+
+func decodeRecordedValue(rv recordedValue) (interface{}, error) {
+	if rv.Type == "null" {
+		return nil, nil
+	}
+	if rv.Type == recordedValuesType {
+		var elems []recordedValue
+		if err := json.Unmarshal(rv.Value, &elems); err != nil {
+			return nil, err
+		}
+		l := make([]interface{}, len(elems))
+		for i, elem := range elems {
+			v, err := decodeRecordedValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = v
+		}
+		return l, nil
+	}
+
+	t, err := parseRecordedType(rv.Type)
+	if err != nil {
+		return nil, err
+	}
+	v, err := decodeJSONValue(rv.Value, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Interface(), nil
+}
+
+// recordedTypeName returns the name used to record values of type t, named
+// types such as Counter are recorded as their underlying type
+func recordedTypeName(t reflect.Type) (string, error) {
+	switch t {
+	case timeType:
+		return "timestamp", nil
+	case uuidType:
+		return "uuid", nil
+	case blobType:
+		return "blob", nil
+	case varintType:
+		return "varint", nil
+	case decimalType:
+		return "decimal", nil
+	case durationType:
+		return "duration", nil
+	case inetType:
+		return "inet", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		elem, err := recordedTypeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "list<" + elem + ">", nil
+	case reflect.Map:
+		key, err := recordedTypeName(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := recordedTypeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "map<" + key + "," + elem + ">", nil
+	case reflect.Interface, reflect.Ptr, reflect.Struct, reflect.Array, reflect.Func, reflect.Chan:
+		return "", fmt.Errorf("Cannot record value of type %s", t)
+	}
+
+	name := t.Kind().String()
+	if _, ok := recordedTypes[name]; !ok {
+		return "", fmt.Errorf("Cannot record value of type %s", t)
+	}
+
+	return name, nil
+}
+
+func parseRecordedType(name string) (reflect.Type, error) {
+	if t, ok := recordedTypes[name]; ok {
+		return t, nil
+	}
+
+	switch {
+	case strings.HasPrefix(name, "list<") && strings.HasSuffix(name, ">"):
+		elem, err := parseRecordedType(name[5 : len(name)-1])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	case strings.HasPrefix(name, "map<") && strings.HasSuffix(name, ">"):
+		inner := name[4 : len(name)-1]
+		// The key type can not be a collection so split on the first comma
+		i := strings.Index(inner, ",")
+		if i < 0 {
+			break
+		}
+		key, err := parseRecordedType(inner[:i])
+		if err != nil {
+			return nil, err
+		}
+		elem, err := parseRecordedType(inner[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, elem), nil
+	}
+
+	return nil, fmt.Errorf("Unknown recorded type %s", name)
+}
+
+// encodeJSONValue converts v into a value which can be marshalled to JSON,
+// maps are encoded as a list of key value pairs as their keys may not be
+// strings
+func encodeJSONValue(v reflect.Value) interface{} {
+	switch v.Type() {
+	case timeType:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano)
+	case uuidType:
+		return v.Interface().(gocql.UUID).String()
+	case blobType, durationType:
+		return v.Interface()
+	case varintType, decimalType:
+		if v.IsNil() {
+			return nil
+		}
+		return v.Interface().(fmt.Stringer).String()
+	case inetType:
+		if v.IsNil() {
+			return nil
+		}
+		return v.Interface().(net.IP).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		l := make([]interface{}, v.Len())
+		for i := range l {
+			l[i] = encodeJSONValue(v.Index(i))
+		}
+		return l
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		pairs := make([][2]interface{}, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			pairs = append(pairs, [2]interface{}{encodeJSONValue(k), encodeJSONValue(v.MapIndex(k))})
+		}
+		// Sort the pairs so that the same map is always recorded the same way
+		sortPairs(pairs)
+		return pairs
+	}
+
+	return v.Convert(recordedTypes[v.Kind().String()]).Interface()
+}
+
+func sortPairs(pairs [][2]interface{}) {
+	sort.Slice(pairs, func(i, j int) bool {
+		a, _ := json.Marshal(pairs[i][0])
+		b, _ := json.Marshal(pairs[j][0])
+		return string(a) < string(b)
+	})
+}
+
+func decodeJSONValue(data json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	switch t {
+	case timeType, uuidType:
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return reflect.Value{}, err
+		}
+		if t == timeType {
+			ts, err := time.Parse(time.RFC3339Nano, s)
+			return reflect.ValueOf(ts), err
+		}
+		uuid, err := gocql.ParseUUID(s)
+		return reflect.ValueOf(uuid), err
+	case blobType:
+		var b []byte
+		err := json.Unmarshal(data, &b)
+		return reflect.ValueOf(b), err
+	case durationType:
+		var d gocql.Duration
+		err := json.Unmarshal(data, &d)
+		return reflect.ValueOf(d), err
+	case varintType, decimalType, inetType:
+		var s *string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return reflect.Value{}, err
+		}
+		if s == nil {
+			return reflect.Zero(t), nil
+		}
+		switch t {
+		case varintType:
+			if i, ok := new(big.Int).SetString(*s, 10); ok {
+				return reflect.ValueOf(i), nil
+			}
+		case decimalType:
+			if d, ok := new(inf.Dec).SetString(*s); ok {
+				return reflect.ValueOf(d), nil
+			}
+		default:
+			// IPv4 addresses are decoded as 4 bytes, as they are by gocql
+			if ip := net.ParseIP(*s); ip != nil {
+				if ip4 := ip.To4(); ip4 != nil {
+					ip = ip4
+				}
+				return reflect.ValueOf(ip), nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("Invalid %s value %q", t, *s)
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return reflect.Value{}, err
+		}
+		if elems == nil {
+			return reflect.Zero(t), nil
+		}
+		l := reflect.MakeSlice(t, len(elems), len(elems))
+		for i, elem := range elems {
+			v, err := decodeJSONValue(elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			l.Index(i).Set(v)
+		}
+		return l, nil
+	case reflect.Map:
+		var pairs [][2]json.RawMessage
+		if err := json.Unmarshal(data, &pairs); err != nil {
+			return reflect.Value{}, err
+		}
+		if pairs == nil {
+			return reflect.Zero(t), nil
+		}
+		m := reflect.MakeMapWithSize(t, len(pairs))
+		for _, pair := range pairs {
+			k, err := decodeJSONValue(pair[0], t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v, err := decodeJSONValue(pair[1], t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			m.SetMapIndex(k, v)
+		}
+		return m, nil
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return v.Elem(), nil
+}
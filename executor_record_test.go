@@ -0,0 +1,154 @@
+package gocassa
+
+import (
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/inf.v0"
+)
+
+type recordedDocument struct {
+	ID      gocql.UUID
+	Created time.Time
+	Tags    []string
+	Counts  map[string]int64
+	Data    []byte
+	Score   float64
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := t.TempDir() + "/fixture.json"
+	doc := recordedDocument{
+		ID:      gocql.TimeUUID(),
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC),
+		Tags:    []string{"a", "b"},
+		Counts:  map[string]int64{"a": 1 << 40, "b": 2},
+		Data:    []byte{1, 2, 3},
+		Score:   1.5,
+	}
+
+	rec := NewRecordingExecutor(NewMemoryExecutor(), path)
+	k := NewKeyspace(rec, "test", nil)
+	tbl := NewMapTable(k, "docs", recordedDocument{}, "id")
+
+	assert.Nil(t, tbl.Set(doc).Execute())
+	read := recordedDocument{}
+	assert.Nil(t, tbl.Read(doc.ID).ScanOne(&read))
+	assert.Equal(t, doc, read)
+	missingID := gocql.TimeUUID()
+	assert.Equal(t, gocql.ErrNotFound, tbl.Read(missingID).ScanOne(&read))
+	docs := []recordedDocument{}
+	assert.Nil(t, tbl.Where(In("id", doc.ID, missingID)).Read().Scan(&docs))
+	assert.Equal(t, []recordedDocument{doc}, docs)
+	assert.Nil(t, rec.Save())
+
+	replay, err := NewReplayExecutor(path)
+	assert.Nil(t, err)
+	k = NewKeyspace(replay, "test", nil)
+	tbl = NewMapTable(k, "docs", recordedDocument{}, "id")
+
+	assert.Nil(t, tbl.Set(doc).Execute())
+	read = recordedDocument{}
+	assert.Nil(t, tbl.Read(doc.ID).ScanOne(&read))
+	assert.Equal(t, doc, read)
+
+	docs = []recordedDocument{}
+	assert.Nil(t, tbl.Where(In("id", doc.ID, missingID)).Read().Scan(&docs))
+	assert.Equal(t, []recordedDocument{doc}, docs)
+
+	rt := &recordingT{}
+	assert.False(t, replay.AssertExpectations(rt))
+	assert.Len(t, rt.errors, 1, "the read of the missing row was not replayed")
+
+	assert.NotNil(t, tbl.Delete(doc.ID).Execute())
+	rt = &recordingT{}
+	assert.False(t, replay.AssertExpectations(rt))
+	assert.Len(t, rt.errors, 2)
+}
+
+func TestRecordedValue(t *testing.T) {
+	for _, v := range []interface{}{
+		nil,
+		"a",
+		true,
+		int(1),
+		int64(1 << 60),
+		uint16(3),
+		float32(1.5),
+		Counter(4),
+		[]int{1, 2},
+		[]string(nil),
+		map[int]string{2: "b", 1: "a"},
+		map[string][]string{"a": {"b"}},
+		[]interface{}{"a", int64(1), nil},
+		big.NewInt(-1 << 62),
+		inf.NewDec(12345, 2),
+		gocql.Duration{Months: 1, Days: 2, Nanoseconds: 3},
+		net.ParseIP("10.0.0.1").To4(),
+		net.ParseIP("2001:db8::1"),
+		[]net.IP{net.ParseIP("10.0.0.2").To4()},
+	} {
+		rv, err := encodeRecordedValue(v)
+		assert.Nil(t, err)
+		decoded, err := decodeRecordedValue(rv)
+		assert.Nil(t, err)
+		if c, ok := v.(Counter); ok {
+			v = int(c)
+		}
+		assert.Equal(t, v, decoded)
+	}
+
+	rv, err := encodeRecordedValue((*string)(nil))
+	assert.Nil(t, err)
+	decoded, err := decodeRecordedValue(rv)
+	assert.Nil(t, err)
+	assert.Nil(t, decoded)
+
+	s := "a"
+	rv, err = encodeRecordedValue(&s)
+	assert.Nil(t, err)
+	decoded, err = decodeRecordedValue(rv)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", decoded)
+
+	_, err = encodeRecordedValue(struct{}{})
+	assert.NotNil(t, err)
+}
+
+func TestRecordingExecutor_iter(t *testing.T) {
+	path := t.TempDir() + "/fixture.json"
+	rec := NewRecordingExecutor(NewMemoryExecutor(), path)
+	k := NewKeyspace(rec, "test", nil)
+	tbl := NewMapTable(k, "docs", recordedDocument{}, "id")
+
+	docs := []recordedDocument{{ID: gocql.TimeUUID()}, {ID: gocql.TimeUUID()}}
+	for _, doc := range docs {
+		assert.Nil(t, tbl.Set(doc).Execute())
+	}
+
+	iter := tbl.Where().Read().Iter()
+	read := recordedDocument{}
+	assert.True(t, iter.Scan(&read))
+	assert.Len(t, rec.rec.Calls, 2, "the iterator is recorded once it is closed")
+	assert.Nil(t, iter.Close())
+	if assert.Len(t, rec.rec.Calls, 3) {
+		assert.Len(t, rec.rec.Calls[2].Rows, 1, "only the scanned rows are recorded")
+	}
+	assert.Nil(t, rec.Save())
+
+	replay, err := NewReplayExecutor(path)
+	assert.Nil(t, err)
+	k = NewKeyspace(replay, "test", nil)
+	tbl = NewMapTable(k, "docs", recordedDocument{}, "id")
+
+	iter = tbl.Where().Read().Iter()
+	replayed := recordedDocument{}
+	assert.True(t, iter.Scan(&replayed))
+	assert.Equal(t, read, replayed)
+	assert.False(t, iter.Scan(&replayed))
+	assert.Nil(t, iter.Close())
+}
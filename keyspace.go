@@ -1,15 +1,31 @@
 package gocassa
 
 import (
+	"bytes"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
 )
 
+// DefaultSchemaAgreementTimeout is used when waiting for schema agreement if no
+// other timeout was specified.
+const DefaultSchemaAgreementTimeout = time.Minute
+
+var schemaAgreementInterval = 200 * time.Millisecond
+
 type Keyspace struct {
 	qe      QueryExecutor
 	name    string
 	options KeyspaceOptions
+
+	schemaMtx sync.RWMutex
+	tables    []*Table
+	objects   []SchemaObject
 }
 
 func NewKeyspace(qe QueryExecutor, name string, options *KeyspaceOptions) *Keyspace {
@@ -32,28 +48,9 @@ func (k *Keyspace) Name() string {
 // CreateStatement returns a CQL which will create the current keyspace if it
 // does not already exist.
 func (k *Keyspace) CreateStatement() string {
-	replicationMap := ""
-	if k.options.ReplicationClass == "" {
-		k.options.ReplicationClass = "SimpleStrategy"
-		k.options.ReplicationFactor = 1
-	}
-	if k.options.ReplicationClass == "SimpleStrategy" {
-		replicationMap = fmt.Sprintf("'class':'SimpleStrategy','replication_factor':%d", k.options.ReplicationFactor)
-	} else if k.options.ReplicationClass == "NetworkTopologyStrategy" {
-		dataCenters := make([]string, 0, len(k.options.DataCenters))
-		for dc, rf := range k.options.DataCenters {
-			dataCenters = append(dataCenters, fmt.Sprintf("'%s':%d", dc, rf))
-		}
-		// Sort to ensure generated CQL is always the same due to the fact that
-		// Go's maps are unordered
-		sort.Strings(dataCenters)
-
-		replicationMap = "'class':'NetworkTopologyStrategy'," + strings.Join(dataCenters, ",")
-	}
-
 	return fmt.Sprintf(
 		"CREATE KEYSPACE IF NOT EXISTS %s WITH REPLICATION = {%s} AND DURABLE_WRITES = %t;",
-		k.Name(), replicationMap, k.options.DurableWrites,
+		k.Name(), k.replicationMap(), k.options.DurableWrites,
 	)
 }
 
@@ -73,6 +70,266 @@ func (k *Keyspace) Drop() error {
 	return k.qe.Execute(NewRawQuery(k.DropStatement(), nil))
 }
 
+// AlterStatement returns a CQL which will update the replication and durable
+// writes settings of the current keyspace to match its options.
+func (k *Keyspace) AlterStatement() string {
+	return fmt.Sprintf(
+		"ALTER KEYSPACE %s WITH REPLICATION = {%s} AND DURABLE_WRITES = %t;",
+		k.Name(), k.replicationMap(), k.options.DurableWrites,
+	)
+}
+
+// Alter attempts to update the replication and durable writes settings of the
+// current keyspace to match its options.
+func (k *Keyspace) Alter() error {
+	return k.qe.Execute(NewRawQuery(k.AlterStatement(), nil))
+}
+
+// KeyspaceReplicationDrift describes the differences between the replication
+// configured in KeyspaceOptions and the replication of the live keyspace.
+type KeyspaceReplicationDrift struct {
+	ExpectedClass string
+	ActualClass   string
+	// DataCenters contains an entry for each data center whose replication
+	// factor differs, sorted by name. For SimpleStrategy keyspaces the single
+	// entry is named "replication_factor".
+	DataCenters []DataCenterDrift
+}
+
+// HasDrift returns true if the live keyspace replication does not match the
+// configured replication.
+func (d KeyspaceReplicationDrift) HasDrift() bool {
+	return d.ExpectedClass != d.ActualClass || len(d.DataCenters) > 0
+}
+
+// DataCenterDrift describes a data center whose live replication factor does
+// not match the configured one. A factor of 0 means the data center is missing.
+type DataCenterDrift struct {
+	DataCenter string
+	Expected   int
+	Actual     int
+}
+
+// byDataCenter sorts DataCenterDrift by data center name
+type byDataCenter []DataCenterDrift
+
+func (x byDataCenter) Len() int { return len(x) }
+
+func (x byDataCenter) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+
+func (x byDataCenter) Less(i, j int) bool {
+	return x[i].DataCenter < x[j].DataCenter
+}
+
+// ReplicationDrift compares the replication configured in the keyspace options
+// with the replication of the live keyspace as stored in
+// system_schema.keyspaces. The returned drift is empty when both match.
+func (k *Keyspace) ReplicationDrift() (*KeyspaceReplicationDrift, error) {
+	m, err := k.qe.QueryOne(NewRawQuery(
+		"SELECT replication FROM system_schema.keyspaces WHERE keyspace_name = ?",
+		[]interface{}{k.Name()},
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	live := map[string]string{}
+	if err := decodeResult(m["replication"], &live); err != nil {
+		return nil, err
+	}
+
+	class, factor, dataCenters := k.replicationOptions()
+	drift := &KeyspaceReplicationDrift{
+		ExpectedClass: class,
+		ActualClass:   live["class"],
+	}
+	// The live class is stored fully qualified, for example
+	// org.apache.cassandra.locator.SimpleStrategy
+	if i := strings.LastIndex(drift.ActualClass, "."); i >= 0 {
+		drift.ActualClass = drift.ActualClass[i+1:]
+	}
+
+	expected := map[string]int{}
+	actual := map[string]int{}
+	if class == "SimpleStrategy" {
+		expected["replication_factor"] = factor
+	} else {
+		for dc, rf := range dataCenters {
+			expected[dc] = rf
+		}
+	}
+	for key, value := range live {
+		if key == "class" {
+			continue
+		}
+		// Transient replication is stored as "<replicas>/<transient>"
+		if i := strings.Index(value, "/"); i >= 0 {
+			value = value[:i]
+		}
+		rf, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replication factor %q for %s", value, key)
+		}
+		actual[key] = rf
+	}
+
+	for key, rf := range expected {
+		if actual[key] != rf {
+			drift.DataCenters = append(drift.DataCenters, DataCenterDrift{
+				DataCenter: key,
+				Expected:   rf,
+				Actual:     actual[key],
+			})
+		}
+	}
+	for key, rf := range actual {
+		if _, ok := expected[key]; !ok {
+			drift.DataCenters = append(drift.DataCenters, DataCenterDrift{
+				DataCenter: key,
+				Actual:     rf,
+			})
+		}
+	}
+	sort.Sort(byDataCenter(drift.DataCenters))
+
+	return drift, nil
+}
+
+// CreateAll attempts to create every object registered with the keyspace if
+// it does not already exist. Types are created first, followed by tables,
+// indexes and views, see SchemaKind.
+func (k *Keyspace) CreateAll() error {
+	for _, o := range k.registeredObjects() {
+		if err := k.qe.Execute(NewRawQuery(o.CreateStatement(), nil)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DropAll attempts to delete every object registered with the keyspace if it
+// exists, in the reverse order that CreateAll would create them.
+func (k *Keyspace) DropAll() error {
+	objects := k.registeredObjects()
+	for i := len(objects) - 1; i >= 0; i-- {
+		if err := k.qe.Execute(NewRawQuery(objects[i].DropStatement(), nil)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TruncateAll attempts to remove all rows from every table registered with the
+// keyspace.
+func (k *Keyspace) TruncateAll() error {
+	for _, t := range k.registeredTables() {
+		if err := t.Truncate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SchemaCQL returns a CQL script which creates the keyspace followed by every
+// object registered with the keyspace, in the order CreateAll creates them.
+// Tables are ordered by name so that the script is stable and can be checked
+// in and reviewed alongside code changes.
+func (k *Keyspace) SchemaCQL() string {
+	buf := new(bytes.Buffer)
+	buf.WriteString(k.CreateStatement())
+	buf.WriteString("\n")
+	for _, o := range k.registeredObjects() {
+		buf.WriteString("\n")
+		// Registered statements may already be terminated
+		buf.WriteString(strings.TrimSuffix(strings.TrimSpace(o.CreateStatement()), ";"))
+		buf.WriteString(";\n")
+	}
+
+	return buf.String()
+}
+
+// Register adds user defined types, indexes, views or any other schema
+// objects to the keyspace so that they are included in CreateAll, DropAll and
+// SchemaCQL. An object replaces any previously registered object of the same
+// kind and name. Tables are registered by NewTable so do not need to be
+// registered again.
+func (k *Keyspace) Register(objects ...SchemaObject) {
+	for _, o := range objects {
+		if t, ok := o.(*Table); ok {
+			k.register(t)
+			continue
+		}
+
+		k.schemaMtx.Lock()
+		replaced := false
+		for i, existing := range k.objects {
+			if existing.SchemaKind() == o.SchemaKind() && existing.Name() == o.Name() {
+				k.objects[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			k.objects = append(k.objects, o)
+		}
+		k.schemaMtx.Unlock()
+	}
+}
+
+// register adds the table to the keyspace, replacing any previously
+// registered table with the same name.
+func (k *Keyspace) register(t *Table) {
+	k.schemaMtx.Lock()
+	defer k.schemaMtx.Unlock()
+
+	for i, existing := range k.tables {
+		if existing.Name() == t.Name() {
+			k.tables[i] = t
+			return
+		}
+	}
+	k.tables = append(k.tables, t)
+}
+
+// registeredTables returns the tables registered with the keyspace sorted by
+// name.
+func (k *Keyspace) registeredTables() []*Table {
+	k.schemaMtx.RLock()
+	tables := make([]*Table, len(k.tables))
+	copy(tables, k.tables)
+	k.schemaMtx.RUnlock()
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name() < tables[j].Name()
+	})
+
+	return tables
+}
+
+// registeredObjects returns every object registered with the keyspace sorted
+// by kind. Tables are sorted by name while other objects are kept in the order
+// they were registered, as a type may use a type registered before it.
+func (k *Keyspace) registeredObjects() []SchemaObject {
+	tables := k.registeredTables()
+
+	k.schemaMtx.RLock()
+	objects := make([]SchemaObject, 0, len(tables)+len(k.objects))
+	for _, t := range tables {
+		objects = append(objects, t)
+	}
+	objects = append(objects, k.objects...)
+	k.schemaMtx.RUnlock()
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].SchemaKind() < objects[j].SchemaKind()
+	})
+
+	return objects
+}
+
 // Returns table names in a keyspace
 func (k *Keyspace) Tables() ([]string, error) {
 	stmt := fmt.Sprintf(
@@ -111,3 +368,107 @@ func (k *Keyspace) TableExists(name string) (bool, error) {
 func (k *Keyspace) QueryExecutor() QueryExecutor {
 	return k.qe
 }
+
+// WithMiddleware wraps the query executor of the keyspace with the given
+// middlewares, see Chain. Tables fetch the executor from their keyspace each
+// time a query is run so all tables in the keyspace, including those which
+// have already been created, use the middlewares. It is not safe to call
+// WithMiddleware while queries are being executed.
+func (k *Keyspace) WithMiddleware(middlewares ...Middleware) *Keyspace {
+	k.qe = Chain(k.qe, middlewares...)
+
+	return k
+}
+
+// AwaitSchemaAgreement blocks until every node in the cluster reports the same
+// schema version, returning an error if they have not agreed once the timeout
+// has elapsed. It should be called after executing DDL statements so that
+// subsequent statements see the new schema.
+func (k *Keyspace) AwaitSchemaAgreement(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		versions, err := k.schemaVersions()
+		if err != nil {
+			return err
+		}
+		if len(versions) <= 1 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("schema versions did not agree within %v: %v", timeout, versions)
+		}
+
+		time.Sleep(schemaAgreementInterval)
+	}
+}
+
+// schemaVersions returns the distinct schema versions reported by the local
+// node and its peers.
+func (k *Keyspace) schemaVersions() ([]string, error) {
+	versions := []string{}
+	seen := map[string]bool{}
+
+	for _, stmt := range []string{
+		"SELECT schema_version FROM system.local",
+		"SELECT schema_version FROM system.peers",
+	} {
+		maps, err := k.qe.Query(NewRawQuery(stmt, nil))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range maps {
+			switch v := m["schema_version"].(type) {
+			case nil:
+				continue
+			case gocql.UUID:
+				// Peers which have not yet reported a schema version
+				if v == (gocql.UUID{}) {
+					continue
+				}
+			}
+
+			version := fmt.Sprint(m["schema_version"])
+			if !seen[version] {
+				seen[version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+// replicationOptions returns the replication class, factor and data centers
+// from the keyspace options, defaulting to SimpleStrategy with a replication
+// factor of 1 if no class was specified.
+func (k *Keyspace) replicationOptions() (string, int, map[string]int) {
+	if k.options.ReplicationClass == "" {
+		return "SimpleStrategy", 1, nil
+	}
+
+	return k.options.ReplicationClass, k.options.ReplicationFactor, k.options.DataCenters
+}
+
+func (k *Keyspace) replicationMap() string {
+	class, factor, dataCenters := k.replicationOptions()
+
+	switch class {
+	case "SimpleStrategy":
+		return fmt.Sprintf("'class':'SimpleStrategy','replication_factor':%d", factor)
+	case "NetworkTopologyStrategy":
+		dcs := make([]string, 0, len(dataCenters))
+		for dc, rf := range dataCenters {
+			dcs = append(dcs, fmt.Sprintf("'%s':%d", dc, rf))
+		}
+		// Sort to ensure generated CQL is always the same due to the fact that
+		// Go's maps are unordered
+		sort.Strings(dcs)
+
+		return "'class':'NetworkTopologyStrategy'," + strings.Join(dcs, ",")
+	}
+
+	return ""
+}
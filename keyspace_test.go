@@ -8,7 +8,7 @@ import (
 )
 
 func TestKeyspaceCreate_noOptions(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		"CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class':'SimpleStrategy','replication_factor':1} AND DURABLE_WRITES = false;",
@@ -23,7 +23,7 @@ func TestKeyspaceCreate_noOptions(t *testing.T) {
 }
 
 func TestKeyspaceCreate_durableWrites(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		"CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class':'SimpleStrategy','replication_factor':1} AND DURABLE_WRITES = true;",
@@ -40,7 +40,7 @@ func TestKeyspaceCreate_durableWrites(t *testing.T) {
 }
 
 func TestKeyspaceCreate_simple(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		"CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class':'SimpleStrategy','replication_factor':3} AND DURABLE_WRITES = false;",
@@ -58,7 +58,7 @@ func TestKeyspaceCreate_simple(t *testing.T) {
 }
 
 func TestKeyspaceCreate_networkSingleDC(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		"CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class':'NetworkTopologyStrategy','dc1':3} AND DURABLE_WRITES = false;",
@@ -78,7 +78,7 @@ func TestKeyspaceCreate_networkSingleDC(t *testing.T) {
 }
 
 func TestKeyspaceCreate_networkMultipleDC(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		"CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class':'NetworkTopologyStrategy','dc1':3,'dc2':3} AND DURABLE_WRITES = false;",
@@ -99,7 +99,7 @@ func TestKeyspaceCreate_networkMultipleDC(t *testing.T) {
 }
 
 func TestKeyspaceDrop(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		"DROP KEYSPACE IF EXISTS test",
@@ -112,3 +112,220 @@ func TestKeyspaceDrop(t *testing.T) {
 	assert.Nil(t, k.Drop())
 	m.AssertExpectations(t)
 }
+
+func TestKeyspaceCreate_doesNotMutateOptions(t *testing.T) {
+	k := NewKeyspace(NewMockExecutor(&mock.Mock{}), "test", nil)
+	k.CreateStatement()
+
+	assert.Equal(t, "", k.options.ReplicationClass)
+	assert.Equal(t, 0, k.options.ReplicationFactor)
+}
+
+func TestKeyspaceAlter(t *testing.T) {
+	m := &mock.Mock{}
+	m.On(
+		"Execute",
+		"ALTER KEYSPACE test WITH REPLICATION = {'class':'NetworkTopologyStrategy','dc1':3,'dc2':3} AND DURABLE_WRITES = true;",
+		[]interface{}(nil),
+	).Return(nil)
+
+	qe := NewMockExecutor(m)
+
+	k := NewKeyspace(qe, "test", &KeyspaceOptions{
+		ReplicationClass: "NetworkTopologyStrategy",
+		DataCenters: map[string]int{
+			"dc1": 3,
+			"dc2": 3,
+		},
+		DurableWrites: true,
+	})
+	assert.Nil(t, k.Alter())
+	m.AssertExpectations(t)
+}
+
+func TestKeyspaceReplicationDrift_none(t *testing.T) {
+	m := &mock.Mock{}
+	m.On(
+		"QueryOne",
+		"SELECT replication FROM system_schema.keyspaces WHERE keyspace_name = ?",
+		[]interface{}{"test"},
+	).Return(map[string]interface{}{
+		"replication": map[string]string{
+			"class": "org.apache.cassandra.locator.NetworkTopologyStrategy",
+			"dc1":   "3",
+		},
+	}, nil)
+
+	qe := NewMockExecutor(m)
+
+	k := NewKeyspace(qe, "test", &KeyspaceOptions{
+		ReplicationClass: "NetworkTopologyStrategy",
+		DataCenters: map[string]int{
+			"dc1": 3,
+		},
+	})
+	drift, err := k.ReplicationDrift()
+	assert.Nil(t, err)
+	assert.False(t, drift.HasDrift())
+	m.AssertExpectations(t)
+}
+
+func TestKeyspaceReplicationDrift_newDataCenter(t *testing.T) {
+	m := &mock.Mock{}
+	m.On(
+		"QueryOne",
+		"SELECT replication FROM system_schema.keyspaces WHERE keyspace_name = ?",
+		[]interface{}{"test"},
+	).Return(map[string]interface{}{
+		"replication": map[string]string{
+			"class": "org.apache.cassandra.locator.NetworkTopologyStrategy",
+			"dc1":   "3",
+			"dc3":   "2",
+		},
+	}, nil)
+
+	qe := NewMockExecutor(m)
+
+	k := NewKeyspace(qe, "test", &KeyspaceOptions{
+		ReplicationClass: "NetworkTopologyStrategy",
+		DataCenters: map[string]int{
+			"dc1": 3,
+			"dc2": 3,
+		},
+	})
+	drift, err := k.ReplicationDrift()
+	assert.Nil(t, err)
+	assert.True(t, drift.HasDrift())
+	assert.Equal(t, []DataCenterDrift{
+		{DataCenter: "dc2", Expected: 3, Actual: 0},
+		{DataCenter: "dc3", Expected: 0, Actual: 2},
+	}, drift.DataCenters)
+	m.AssertExpectations(t)
+}
+
+func TestKeyspaceReplicationDrift_class(t *testing.T) {
+	m := &mock.Mock{}
+	m.On(
+		"QueryOne",
+		"SELECT replication FROM system_schema.keyspaces WHERE keyspace_name = ?",
+		[]interface{}{"test"},
+	).Return(map[string]interface{}{
+		"replication": map[string]string{
+			"class":              "org.apache.cassandra.locator.SimpleStrategy",
+			"replication_factor": "1",
+		},
+	}, nil)
+
+	qe := NewMockExecutor(m)
+
+	k := NewKeyspace(qe, "test", &KeyspaceOptions{
+		ReplicationClass: "NetworkTopologyStrategy",
+		DataCenters: map[string]int{
+			"dc1": 3,
+		},
+	})
+	drift, err := k.ReplicationDrift()
+	assert.Nil(t, err)
+	assert.Equal(t, "NetworkTopologyStrategy", drift.ExpectedClass)
+	assert.Equal(t, "SimpleStrategy", drift.ActualClass)
+	assert.Equal(t, []DataCenterDrift{
+		{DataCenter: "dc1", Expected: 3, Actual: 0},
+		{DataCenter: "replication_factor", Expected: 0, Actual: 1},
+	}, drift.DataCenters)
+	m.AssertExpectations(t)
+}
+
+func TestKeyspaceSchemaCQL(t *testing.T) {
+	k := NewKeyspace(NewMockExecutor(&mock.Mock{}), "test", nil)
+	NewTable(k, "b", Document{}, []string{"fielda"}, []string{"fieldb"}, nil)
+	NewMapTable(k, "a", Document{}, "fielda")
+	NewTable(k, "b", Document{}, []string{"fielda"}, nil, nil)
+
+	assert.Equal(t, `CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class':'SimpleStrategy','replication_factor':1} AND DURABLE_WRITES = false;
+
+CREATE TABLE IF NOT EXISTS test.a (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda));
+
+CREATE TABLE IF NOT EXISTS test.b (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda));
+`, k.SchemaCQL())
+}
+
+func TestKeyspaceCreateAll(t *testing.T) {
+	m := &mock.Mock{}
+	m.On(
+		"Execute",
+		`CREATE TABLE IF NOT EXISTS test.a (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda))`,
+		[]interface{}(nil),
+	).Return(nil)
+	m.On(
+		"Execute",
+		`CREATE TABLE IF NOT EXISTS test.b (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda,fieldb))`,
+		[]interface{}(nil),
+	).Return(nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	NewTable(k, "b", Document{}, []string{"fielda"}, []string{"fieldb"}, nil)
+	NewMapTable(k, "a", Document{}, "fielda")
+
+	assert.Nil(t, k.CreateAll())
+	m.AssertExpectations(t)
+}
+
+func TestKeyspaceDropAll(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Execute", "DROP TABLE IF EXISTS test.a", []interface{}(nil)).Return(nil)
+	m.On("Execute", "DROP TABLE IF EXISTS test.b", []interface{}(nil)).Return(nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	NewTable(k, "b", Document{}, []string{"fielda"}, nil, nil)
+	NewMapTable(k, "a", Document{}, "fielda")
+
+	assert.Nil(t, k.DropAll())
+	m.AssertExpectations(t)
+}
+
+func TestKeyspaceTruncateAll(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Execute", "TRUNCATE TABLE test.a", []interface{}(nil)).Return(nil)
+	m.On("Execute", "TRUNCATE TABLE test.b", []interface{}(nil)).Return(nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	NewTable(k, "b", Document{}, []string{"fielda"}, nil, nil)
+	NewMapTable(k, "a", Document{}, "fielda")
+
+	assert.Nil(t, k.TruncateAll())
+	m.AssertExpectations(t)
+}
+
+func TestKeyspaceRegister_order(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	k.Register(
+		NewSchemaObject(SchemaView, "docs_by_b", "CREATE MATERIALIZED VIEW IF NOT EXISTS test.docs_by_b AS SELECT ...", "DROP MATERIALIZED VIEW IF EXISTS test.docs_by_b"),
+		NewSchemaObject(SchemaIndex, "docs_c", "CREATE INDEX IF NOT EXISTS docs_c ON test.docs (fieldc)", "DROP INDEX IF EXISTS test.docs_c"),
+		NewSchemaObject(SchemaType, "point", "CREATE TYPE IF NOT EXISTS test.point (x int,y int)", "DROP TYPE IF EXISTS test.point"),
+		NewSchemaObject(SchemaType, "shape", "CREATE TYPE IF NOT EXISTS test.shape (points list<frozen<point>>);\n", "DROP TYPE IF EXISTS test.shape"),
+	)
+	NewMapTable(k, "docs", Document{}, "fielda")
+
+	assert.Equal(t, `CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class':'SimpleStrategy','replication_factor':1} AND DURABLE_WRITES = false;
+
+CREATE TYPE IF NOT EXISTS test.point (x int,y int);
+
+CREATE TYPE IF NOT EXISTS test.shape (points list<frozen<point>>);
+
+CREATE TABLE IF NOT EXISTS test.docs (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda));
+
+CREATE INDEX IF NOT EXISTS docs_c ON test.docs (fieldc);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS test.docs_by_b AS SELECT ...;
+`, k.SchemaCQL())
+
+	qe.InOrder()
+	qe.ExpectRaw("DROP MATERIALIZED VIEW IF EXISTS test.docs_by_b")
+	qe.ExpectRaw("DROP INDEX IF EXISTS test.docs_c")
+	qe.ExpectRaw("DROP TABLE IF EXISTS test.docs")
+	qe.ExpectRaw("DROP TYPE IF EXISTS test.shape")
+	qe.ExpectRaw("DROP TYPE IF EXISTS test.point")
+	assert.Nil(t, k.DropAll())
+	qe.AssertExpectations(t)
+}
@@ -0,0 +1,84 @@
+package gocassa
+
+// LogLevel is the severity of a log entry
+type LogLevel uint8
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	default:
+		return ""
+	}
+}
+
+// A Logger receives log entries from the query executor and can be used to
+// connect gocassa to any logging library.
+type Logger interface {
+	// Log writes a log entry, fields contains structured data such as the
+	// statement being executed.
+	Log(level LogLevel, msg string, fields map[string]interface{})
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as loggers.
+type LoggerFunc func(level LogLevel, msg string, fields map[string]interface{})
+
+// Log calls f(level, msg, fields)
+func (f LoggerFunc) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	f(level, msg, fields)
+}
+
+// NopLogger discards all log entries, it is used by the executor if no logger
+// is specified.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(level LogLevel, msg string, fields map[string]interface{}) {}
+
+// redactedValue replaces the values of sensitive columns in log entries
+const redactedValue = "<redacted>"
+
+// A redactedStatementGenerator is a query which knows which of its values
+// belong to sensitive columns.
+type redactedStatementGenerator interface {
+	// generateRedactedStatement returns the same statement and values as
+	// GenerateStatement except that the values of sensitive columns are
+	// replaced with redactedValue.
+	generateRedactedStatement() (stmt string, values []interface{})
+}
+
+// logStatement writes a log entry for the statement using the logger in the
+// given options. The statement is redacted if the query supports it, values
+// are only included if enabled in the options.
+func logStatement(options ExecutorOptions, msg string, query QueryGenerator, stmt string, values []interface{}) {
+	if options.Logger == nil || options.Logger == NopLogger {
+		return
+	}
+
+	if q, ok := query.(redactedStatementGenerator); ok {
+		stmt, values = q.generateRedactedStatement()
+	}
+
+	fields := map[string]interface{}{
+		"statement": stmt,
+	}
+	if options.LogValues {
+		fields["values"] = values
+	}
+
+	options.Logger.Log(options.LogLevel, msg, fields)
+}
@@ -0,0 +1,90 @@
+package gocassa
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type SensitiveDocument struct {
+	ID       string
+	Email    string   `cql:"email,sensitive"`
+	Tags     []string `cql:",sensitive"`
+	Nickname string
+}
+
+type logEntry struct {
+	level  LogLevel
+	msg    string
+	fields map[string]interface{}
+}
+
+func captureLogger(entries *[]logEntry) Logger {
+	return LoggerFunc(func(level LogLevel, msg string, fields map[string]interface{}) {
+		*entries = append(*entries, logEntry{level, msg, fields})
+	})
+}
+
+func TestLogStatement_statementOnly(t *testing.T) {
+	entries := []logEntry{}
+	options := ExecutorOptions{Logger: captureLogger(&entries), LogLevel: LogLevelInfo}
+
+	k := NewKeyspace(NewMockExecutor(&mock.Mock{}), "test", nil)
+	tbl := NewTable(k, "test", SensitiveDocument{}, []string{"id"}, nil, nil)
+	q := tbl.Set(SensitiveDocument{ID: "1", Email: "john@example.com", Nickname: "john"}).Query
+
+	stmt, values := q.GenerateStatement()
+	logStatement(options, "Executing query", q, stmt, values)
+
+	assert.Equal(t, []logEntry{{
+		level: LogLevelInfo,
+		msg:   "Executing query",
+		fields: map[string]interface{}{
+			"statement": "UPDATE test.test SET email = ?,nickname = ?,tags = ? WHERE id = ?",
+		},
+	}}, entries)
+}
+
+func TestLogStatement_redactedValues(t *testing.T) {
+	entries := []logEntry{}
+	options := ExecutorOptions{Logger: captureLogger(&entries), LogValues: true}
+
+	k := NewKeyspace(NewMockExecutor(&mock.Mock{}), "test", nil)
+	tbl := NewTable(k, "test", SensitiveDocument{}, []string{"id"}, nil, nil)
+
+	q := tbl.Where(Eq("email", "john@example.com")).Update(map[string]interface{}{
+		"nickname": "john",
+		"tags":     ListAppend("secret"),
+	}).Query
+	stmt, values := q.GenerateStatement()
+	logStatement(options, "Executing query", q, stmt, values)
+
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, LogLevelDebug, entries[0].level)
+		assert.Equal(t, map[string]interface{}{
+			"statement": "UPDATE test.test SET nickname = ?,tags = <redacted> WHERE email = ?",
+			"values":    []interface{}{"john", "<redacted>"},
+		}, entries[0].fields)
+	}
+
+	// The executed statement must not be affected by redaction
+	assert.Equal(t, "UPDATE test.test SET nickname = ?,tags = tags + ['secret'] WHERE email = ?", stmt)
+	assert.Equal(t, []interface{}{"john", "john@example.com"}, values)
+}
+
+func TestLogStatement_rawQuery(t *testing.T) {
+	entries := []logEntry{}
+	options := ExecutorOptions{Logger: captureLogger(&entries), LogValues: true}
+
+	q := NewRawQuery("SELECT * FROM test.test WHERE id = ?", []interface{}{"1"})
+	stmt, values := q.GenerateStatement()
+	logStatement(options, "Executing query", q, stmt, values)
+
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, map[string]interface{}{
+			"statement": "SELECT * FROM test.test WHERE id = ?",
+			"values":    []interface{}{"1"},
+		}, entries[0].fields)
+	}
+}
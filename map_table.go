@@ -1,5 +1,11 @@
 package gocassa
 
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
 type MapTable struct {
 	*Table
 
@@ -29,6 +35,137 @@ func (t *MapTable) MultiRead(ids []interface{}) RunnableQuery {
 	return t.Where(In(t.partitionKey, ids...)).Read()
 }
 
+// MultiReadContext reads the partitions with the given ids into the slice
+// pointed at by dest and returns the ids which were not found. The results are
+// in the same order as ids, with missing ids skipped.
+//
+// If options is nil the MultiRead options of the table are used. By default
+// the partitions are read with a single IN query, if FanOut is set each
+// partition is read with its own query instead, with at most Concurrency
+// queries in flight. The first error cancels any remaining reads.
+func (t *MapTable) MultiReadContext(ctx context.Context, ids []interface{}, dest interface{}, options *MultiReadOptions) (missing []interface{}, err error) {
+	if options == nil {
+		options = t.options.MultiRead
+	}
+	if options == nil {
+		options = &MultiReadOptions{}
+	}
+
+	var rows []map[string]interface{}
+	if options.FanOut {
+		rows, err = t.fanOutRead(ctx, ids, options.Concurrency)
+	} else {
+		rows, err = t.inRead(ctx, ids)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]map[string]interface{}, 0, len(rows))
+	for i, row := range rows {
+		if row == nil {
+			missing = append(missing, ids[i])
+			continue
+		}
+		found = append(found, row)
+	}
+
+	return missing, decodeResult(found, dest)
+}
+
+// inRead reads the partitions using a single IN query and returns the row of
+// each id, or nil if it was not found
+func (t *MapTable) inRead(ctx context.Context, ids []interface{}) ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, len(ids))
+	if len(ids) == 0 {
+		return rows, nil
+	}
+
+	q := t.MultiRead(ids)
+	results, err := q.Executor.QueryContext(ctx, q.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	info := t.columnType(t.partitionKey)
+	byKey := make(map[string]map[string]interface{}, len(results))
+	for _, row := range results {
+		value, ok := row[t.partitionKey]
+		if !ok {
+			value = row[strings.ToLower(t.partitionKey)]
+		}
+		byKey[keyBytes(info, value)] = row
+	}
+	for i, id := range ids {
+		rows[i] = byKey[keyBytes(info, id)]
+	}
+
+	return rows, nil
+}
+
+// fanOutRead reads each partition with its own query and returns the row of
+// each id, or nil if it was not found
+func (t *MapTable) fanOutRead(ctx context.Context, ids []interface{}, concurrency int) ([]map[string]interface{}, error) {
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+	if concurrency > len(ids) {
+		concurrency = len(ids)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rows := make([]map[string]interface{}, len(ids))
+	indexes := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				q := t.Read(ids[i])
+				results, err := q.Executor.QueryContext(ctx, q.Query)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				if len(results) > 0 {
+					rows[i] = results[0]
+				}
+			}
+		}()
+	}
+
+send:
+	for i := range ids {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
 func (t *MapTable) WithOptions(options TableOptions) *MapTable {
 	t.Table = t.Table.WithOptions(options)
 	return t
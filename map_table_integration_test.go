@@ -18,8 +18,7 @@ func TestIntegrationMapTable(t *testing.T) {
 
 	tbl := NewMapTable(keyspace, "map_table", Document{}, "fielda")
 
-	assert.Nil(t, tbl.Drop())
-	assert.Nil(t, tbl.Create())
+	assert.Nil(t, tbl.Recreate())
 
 	t.Run("Set", func(t *testing.T) {
 		assert.Nil(t, tbl.Set(Document{
@@ -0,0 +1,78 @@
+package gocassa
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapTableMultiReadContext(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id")
+	for _, id := range []string{"a", "b", "c"} {
+		assert.Nil(t, tbl.Set(memoryDocument{ID: id}).Execute())
+	}
+
+	ids := []interface{}{"c", "x", "a", "b"}
+	for _, options := range []*MultiReadOptions{
+		nil,
+		{FanOut: true},
+		{FanOut: true, Concurrency: 1},
+	} {
+		docs := []memoryDocument{}
+		missing, err := tbl.MultiReadContext(context.Background(), ids, &docs, options)
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{"x"}, missing)
+		assert.Equal(t, []memoryDocument{{ID: "c"}, {ID: "a"}, {ID: "b"}}, docs)
+	}
+}
+
+func TestMapTableMultiReadContext_tableOptions(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewMapTable(k, "docs", memoryDocument{}, "id").WithOptions(TableOptions{
+		MultiRead: &MultiReadOptions{FanOut: true, Concurrency: 2},
+	})
+
+	qe.ExpectSelect("docs").Where("id", "a").Return([]map[string]interface{}{{"id": "a"}})
+	qe.ExpectSelect("docs").Where("id", "b").ReturnError(errors.New("Unavailable"))
+
+	docs := []memoryDocument{}
+	_, err := tbl.MultiReadContext(context.Background(), []interface{}{"a", "b"}, &docs, nil)
+	assert.EqualError(t, err, "Unavailable")
+}
+
+func TestMapTableMultiReadContext_idTypes(t *testing.T) {
+	type event struct {
+		ID      gocql.UUID
+		Created time.Time
+	}
+
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+
+	id := gocql.TimeUUID()
+	uuids := NewMapTable(k, "uuids", event{}, "id")
+	qe.ExpectSelect("uuids").Return([]map[string]interface{}{{"id": id}})
+
+	docs := []event{}
+	missing, err := uuids.MultiReadContext(context.Background(), []interface{}{id.String(), gocql.TimeUUID().String()}, &docs, nil)
+	assert.Nil(t, err)
+	assert.Len(t, missing, 1)
+	assert.Equal(t, []event{{ID: id}}, docs)
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	times := NewMapTable(k, "times", event{}, "created")
+	qe.ExpectSelect("times").Return([]map[string]interface{}{{"created": created}})
+
+	docs = []event{}
+	missing, err = times.MultiReadContext(context.Background(), []interface{}{created.In(time.FixedZone("CET", 3600))}, &docs, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, missing)
+	assert.Equal(t, []event{{Created: created}}, docs)
+	qe.AssertExpectations(t)
+}
@@ -0,0 +1,90 @@
+package gocassa
+
+import "context"
+
+// A Middleware wraps a query executor to add behaviour such as logging,
+// metrics, retries or rate limiting to every query.
+type Middleware func(QueryExecutor) QueryExecutor
+
+// Chain wraps the query executor with each of the given middlewares. The first
+// middleware is the outermost, so it is called first and sees the results of
+// all of the others.
+func Chain(qe QueryExecutor, middlewares ...Middleware) QueryExecutor {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		qe = middlewares[i](qe)
+	}
+
+	return qe
+}
+
+// ExecutorWrapper implements QueryExecutor by forwarding every method to Next.
+// Middlewares can embed it and only override the methods they need.
+//
+// Each method is forwarded to the same method of Next, so the methods without
+// a context do not call their context variants. Middlewares which need to see
+// every query should override both, for example Query and QueryContext.
+type ExecutorWrapper struct {
+	Next QueryExecutor
+}
+
+func (w ExecutorWrapper) QueryOne(query QueryGenerator) (map[string]interface{}, error) {
+	return w.Next.QueryOne(query)
+}
+
+func (w ExecutorWrapper) QueryOneContext(ctx context.Context, query QueryGenerator) (map[string]interface{}, error) {
+	return w.Next.QueryOneContext(ctx, query)
+}
+
+func (w ExecutorWrapper) QueryCAS(query QueryGenerator) (result map[string]interface{}, applied bool, err error) {
+	return w.Next.QueryCAS(query)
+}
+
+func (w ExecutorWrapper) Query(query QueryGenerator) ([]map[string]interface{}, error) {
+	return w.Next.Query(query)
+}
+
+func (w ExecutorWrapper) QueryContext(ctx context.Context, query QueryGenerator) ([]map[string]interface{}, error) {
+	return w.Next.QueryContext(ctx, query)
+}
+
+func (w ExecutorWrapper) Iter(query QueryGenerator) Iter {
+	return w.Next.Iter(query)
+}
+
+func (w ExecutorWrapper) IterContext(ctx context.Context, query QueryGenerator) Iter {
+	return w.Next.IterContext(ctx, query)
+}
+
+func (w ExecutorWrapper) Execute(query QueryGenerator) error {
+	return w.Next.Execute(query)
+}
+
+func (w ExecutorWrapper) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	return w.Next.ExecuteContext(ctx, query)
+}
+
+func (w ExecutorWrapper) ExecuteBatch(queries []QueryGenerator, options QueryOptions) error {
+	return w.Next.ExecuteBatch(queries, options)
+}
+
+func (w ExecutorWrapper) ExecuteBatchContext(ctx context.Context, queries []QueryGenerator, options QueryOptions) error {
+	return w.Next.ExecuteBatchContext(ctx, queries, options)
+}
+
+func (w ExecutorWrapper) ExecuteBatchCAS(
+	queries []QueryGenerator, options QueryOptions,
+) (
+	result map[string]interface{}, iter Iter, applied bool, err error,
+) {
+	return w.Next.ExecuteBatchCAS(queries, options)
+}
+
+func (w ExecutorWrapper) Close() {
+	w.Next.Close()
+}
+
+// Go runs fn using the worker pool of Next if it implements AsyncExecutor, so
+// that wrapped executors keep the same limit on asynchronous queries.
+func (w ExecutorWrapper) Go(ctx context.Context, fn func()) error {
+	return runAsync(ctx, w.Next, fn)
+}
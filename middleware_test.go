@@ -0,0 +1,74 @@
+package gocassa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type recordingMiddleware struct {
+	ExecutorWrapper
+	name  string
+	calls *[]string
+}
+
+func recordExecute(name string, calls *[]string) Middleware {
+	return func(next QueryExecutor) QueryExecutor {
+		return recordingMiddleware{ExecutorWrapper{next}, name, calls}
+	}
+}
+
+func (w recordingMiddleware) Execute(query QueryGenerator) error {
+	*w.calls = append(*w.calls, w.name)
+	return w.Next.Execute(query)
+}
+
+func (w recordingMiddleware) ExecuteContext(ctx context.Context, query QueryGenerator) error {
+	*w.calls = append(*w.calls, w.name+"Context")
+	return w.Next.ExecuteContext(ctx, query)
+}
+
+func TestChain(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Execute", "TRUNCATE test.test", []interface{}(nil)).Return(nil).Times(2)
+
+	calls := []string{}
+	qe := Chain(NewMockExecutor(m), recordExecute("a", &calls), recordExecute("b", &calls))
+
+	assert.Nil(t, qe.Execute(NewRawQuery("TRUNCATE test.test", nil)))
+	assert.Nil(t, qe.ExecuteContext(context.Background(), NewRawQuery("TRUNCATE test.test", nil)))
+	assert.Equal(t, []string{"a", "b", "aContext", "bContext"}, calls)
+	m.AssertExpectations(t)
+}
+
+func TestChain_forwardsOtherMethods(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Query", "SELECT * FROM test.test", []interface{}(nil)).Return([]map[string]interface{}{
+		{"id": "1"},
+	}, nil)
+
+	calls := []string{}
+	qe := Chain(NewMockExecutor(m), recordExecute("a", &calls))
+
+	rows, err := qe.Query(NewRawQuery("SELECT * FROM test.test", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{{"id": "1"}}, rows)
+	assert.Empty(t, calls)
+	m.AssertExpectations(t)
+}
+
+func TestKeyspaceWithMiddleware(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Execute", "UPDATE test.test SET fieldb = ?,fieldc = ?,fieldd = ? WHERE fielda = ?", []interface{}{"b", "c", "d", "a"}).Return(nil)
+
+	calls := []string{}
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+	k.WithMiddleware(recordExecute("a", &calls))
+
+	assert.Nil(t, tbl.Set(Document{"a", "b", "c", "d"}).Execute())
+	assert.Equal(t, []string{"aContext"}, calls)
+	m.AssertExpectations(t)
+}
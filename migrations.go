@@ -0,0 +1,469 @@
+package gocassa
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	defaultMigrationsTableName = "schema_migrations"
+	defaultMigrationsLockTTL   = 5 * time.Minute
+	migrationsLockID           = "lock"
+)
+
+var migrationsLockInterval = time.Second
+
+// ErrMigrationLockLost is returned by a Migrator if the migration lock could
+// not be renewed, for example because it expired and was taken by another
+// instance. No further migrations are run once the lock is lost.
+var ErrMigrationLockLost = errors.New("Migration lock was lost, consider increasing the lock TTL")
+
+// A MigrationFunc applies or reverts a migration using Go code, for example to
+// backfill data after a column has been added.
+type MigrationFunc func(k *Keyspace) error
+
+// A Migration describes a versioned change to the schema of a keyspace.
+// Migrations are applied in ascending order of version and reverted in
+// descending order.
+type Migration struct {
+	Version     int64
+	Description string
+
+	// Up and Down are CQL statements which are executed in order when the
+	// migration is applied or reverted. The migrator waits for schema
+	// agreement after each statement.
+	Up   []string
+	Down []string
+
+	// UpFunc and DownFunc are run after the CQL statements when the migration
+	// is applied or reverted.
+	UpFunc   MigrationFunc
+	DownFunc MigrationFunc
+}
+
+// MigrationStatus describes whether a migration has been applied to the
+// keyspace.
+type MigrationStatus struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type MigratorOptions struct {
+	// TableName is the name of the table used to record applied migrations,
+	// defaults to schema_migrations. The lock is stored in a table with the
+	// same name suffixed with _lock.
+	TableName string
+	// LockTTL is how long the migration lock is held before it expires if the
+	// instance holding it dies, defaults to 5 minutes. It is rounded up to
+	// whole seconds. The lock is renewed every third of the TTL while
+	// migrations run.
+	LockTTL time.Duration
+	// LockTimeout is how long to wait for another instance to release the
+	// migration lock. If zero a MigrationLockError is returned immediately.
+	LockTimeout time.Duration
+	// SchemaAgreementTimeout is how long to wait for schema agreement after
+	// each DDL statement, defaults to DefaultSchemaAgreementTimeout.
+	SchemaAgreementTimeout time.Duration
+}
+
+// A Migrator applies and reverts registered migrations to a keyspace, keeping
+// a history of applied migrations in a table within the keyspace. A
+// lightweight transaction is used to ensure that only one instance runs
+// migrations at a time.
+type Migrator struct {
+	keyspace   *Keyspace
+	migrations []Migration
+	options    MigratorOptions
+	owner      string
+}
+
+// NewMigrator creates a new migrator for the given keyspace.
+func NewMigrator(keyspace *Keyspace, options *MigratorOptions) *Migrator {
+	var opts MigratorOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.TableName == "" {
+		opts.TableName = defaultMigrationsTableName
+	}
+	if opts.LockTTL == 0 {
+		opts.LockTTL = defaultMigrationsLockTTL
+	}
+	if opts.SchemaAgreementTimeout == 0 {
+		opts.SchemaAgreementTimeout = DefaultSchemaAgreementTimeout
+	}
+
+	return &Migrator{
+		keyspace: keyspace,
+		options:  opts,
+		owner:    gocql.TimeUUID().String(),
+	}
+}
+
+// Register adds migrations to the migrator.
+func (m *Migrator) Register(migrations ...Migration) *Migrator {
+	m.migrations = append(m.migrations, migrations...)
+	return m
+}
+
+// Up applies all registered migrations which have not yet been applied.
+func (m *Migrator) Up() error {
+	migrations, err := m.sortedMigrations()
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(func(held func() error) error {
+		applied, err := m.appliedMigrations()
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if err := held(); err != nil {
+				return err
+			}
+			if err := m.run(migration.Up, migration.UpFunc); err != nil {
+				return fmt.Errorf("Migration %d (%s) failed: %v", migration.Version, migration.Description, err)
+			}
+			if err := m.execute(NewRawQuery(
+				fmt.Sprintf("INSERT INTO %s.%s (version,description,applied_at) VALUES (?,?,?)", m.keyspace.Name(), m.options.TableName),
+				[]interface{}{migration.Version, migration.Description, time.Now()},
+			)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down() error {
+	migrations, err := m.sortedMigrations()
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(func(held func() error) error {
+		applied, err := m.appliedMigrations()
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if len(migration.Down) == 0 && migration.DownFunc == nil {
+				return fmt.Errorf("Migration %d (%s) cannot be reverted", migration.Version, migration.Description)
+			}
+			if err := held(); err != nil {
+				return err
+			}
+			if err := m.run(migration.Down, migration.DownFunc); err != nil {
+				return fmt.Errorf("Reverting migration %d (%s) failed: %v", migration.Version, migration.Description, err)
+			}
+
+			return m.execute(NewRawQuery(
+				fmt.Sprintf("DELETE FROM %s.%s WHERE version = ?", m.keyspace.Name(), m.options.TableName),
+				[]interface{}{migration.Version},
+			))
+		}
+
+		return nil
+	})
+}
+
+// Status returns the status of every registered migration, as well as any
+// applied migrations which are no longer registered, ordered by version.
+// Status does not modify the keyspace, if the history table has not been
+// created yet every migration is reported as not applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	migrations, err := m.sortedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]migrationRecord{}
+	exists, err := m.historyTableExists()
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if applied, err = m.appliedMigrations(); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		ret = append(ret, status)
+	}
+	for _, record := range applied {
+		ret = append(ret, MigrationStatus{
+			Migration: Migration{
+				Version:     record.Version,
+				Description: record.Description,
+			},
+			Applied:   true,
+			AppliedAt: record.AppliedAt,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Migration.Version < ret[j].Migration.Version
+	})
+
+	return ret, nil
+}
+
+type migrationRecord struct {
+	Version     int64
+	Description string
+	AppliedAt   time.Time `cql:"applied_at"`
+}
+
+func (m *Migrator) sortedMigrations() ([]Migration, error) {
+	migrations := make([]Migration, len(m.migrations))
+	copy(migrations, m.migrations)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, migration := range migrations {
+		if len(migration.Up) == 0 && migration.UpFunc == nil {
+			return nil, fmt.Errorf("Migration %d (%s) has nothing to apply", migration.Version, migration.Description)
+		}
+		if i > 0 && migrations[i-1].Version == migration.Version {
+			return nil, fmt.Errorf("Migration %d is registered more than once", migration.Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+func (m *Migrator) appliedMigrations() (map[int64]migrationRecord, error) {
+	maps, err := m.keyspace.QueryExecutor().Query(NewRawQuery(
+		fmt.Sprintf("SELECT version,description,applied_at FROM %s.%s", m.keyspace.Name(), m.options.TableName),
+		nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	records := []migrationRecord{}
+	if err := decodeResult(maps, &records); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[int64]migrationRecord, len(records))
+	for _, record := range records {
+		ret[record.Version] = record
+	}
+
+	return ret, nil
+}
+
+func (m *Migrator) historyTableExists() (bool, error) {
+	maps, err := m.keyspace.QueryExecutor().Query(NewRawQuery(
+		"SELECT table_name FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?",
+		[]interface{}{m.keyspace.Name(), m.options.TableName},
+	))
+	if err != nil {
+		return false, err
+	}
+
+	return len(maps) > 0, nil
+}
+
+// run executes the given CQL statements, waiting for schema agreement after
+// each one, followed by the Go function if any.
+func (m *Migrator) run(stmts []string, fn MigrationFunc) error {
+	for _, stmt := range stmts {
+		if err := m.executeDDL(stmt); err != nil {
+			return err
+		}
+	}
+
+	if fn != nil {
+		if err := fn(m.keyspace); err != nil {
+			return err
+		}
+
+		return m.keyspace.AwaitSchemaAgreement(m.options.SchemaAgreementTimeout)
+	}
+
+	return nil
+}
+
+func (m *Migrator) createTables() error {
+	if err := m.executeDDL(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s.%s (version bigint,description varchar,applied_at timestamp,PRIMARY KEY (version))",
+		m.keyspace.Name(), m.options.TableName,
+	)); err != nil {
+		return err
+	}
+
+	return m.executeDDL(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s.%s_lock (id varchar,owner varchar,PRIMARY KEY (id))",
+		m.keyspace.Name(), m.options.TableName,
+	))
+}
+
+// withLock creates the migration tables if required and runs fn while holding
+// the migration lock. The lock is renewed while fn runs so that migrations
+// which take longer than the lock TTL keep it, fn must call held before each
+// migration which returns ErrMigrationLockLost if a renewal failed.
+func (m *Migrator) withLock(fn func(held func() error) error) (err error) {
+	if err := m.createTables(); err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	lost := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		if !m.renewLock(stop) {
+			close(lost)
+		}
+	}()
+	held := func() error {
+		select {
+		case <-lost:
+			return ErrMigrationLockLost
+		default:
+			return nil
+		}
+	}
+	defer func() {
+		close(stop)
+		<-renewed
+		if held() != nil {
+			if err == nil {
+				err = ErrMigrationLockLost
+			}
+			return
+		}
+		if unlockErr := m.unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	return fn(held)
+}
+
+// lockTTL returns the TTL of the lock in whole seconds, rounded up so that a
+// sub-second TTL does not become a lock which never expires
+func (m *Migrator) lockTTL() int64 {
+	ttl := int64((m.options.LockTTL + time.Second - 1) / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	return ttl
+}
+
+func (m *Migrator) lock() error {
+	deadline := time.Now().Add(m.options.LockTimeout)
+	query := NewRawQuery(
+		fmt.Sprintf(
+			"INSERT INTO %s.%s_lock (id,owner) VALUES (?,?) IF NOT EXISTS USING TTL %d",
+			m.keyspace.Name(), m.options.TableName, m.lockTTL(),
+		),
+		[]interface{}{migrationsLockID, m.owner},
+	)
+
+	for {
+		result, applied, err := m.keyspace.QueryExecutor().QueryCAS(query)
+		if err != nil {
+			return err
+		}
+		if applied {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return MigrationLockError{Owner: fmt.Sprint(result["owner"])}
+		}
+
+		time.Sleep(migrationsLockInterval)
+	}
+}
+
+// renewLock resets the TTL of the migration lock every third of the TTL until
+// stop is closed. Failed renewals are retried on the next tick, false is
+// returned if the lock is no longer held by this migrator.
+func (m *Migrator) renewLock(stop <-chan struct{}) bool {
+	interval := m.options.LockTTL / 3
+	if interval <= 0 {
+		interval = migrationsLockInterval
+	}
+	query := NewRawQuery(
+		fmt.Sprintf(
+			"UPDATE %s.%s_lock USING TTL %d SET owner = ? WHERE id = ? IF owner = ?",
+			m.keyspace.Name(), m.options.TableName, m.lockTTL(),
+		),
+		[]interface{}{m.owner, migrationsLockID, m.owner},
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return true
+		case <-ticker.C:
+			_, applied, err := m.keyspace.QueryExecutor().QueryCAS(query)
+			if err == nil && !applied {
+				return false
+			}
+		}
+	}
+}
+
+func (m *Migrator) unlock() error {
+	_, applied, err := m.keyspace.QueryExecutor().QueryCAS(NewRawQuery(
+		fmt.Sprintf("DELETE FROM %s.%s_lock WHERE id = ? IF owner = ?", m.keyspace.Name(), m.options.TableName),
+		[]interface{}{migrationsLockID, m.owner},
+	))
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrMigrationLockLost
+	}
+
+	return nil
+}
+
+func (m *Migrator) execute(query QueryGenerator) error {
+	return m.keyspace.QueryExecutor().Execute(query)
+}
+
+func (m *Migrator) executeDDL(stmt string) error {
+	if err := m.execute(NewRawQuery(stmt, nil)); err != nil {
+		return err
+	}
+
+	return m.keyspace.AwaitSchemaAgreement(m.options.SchemaAgreementTimeout)
+}
@@ -0,0 +1,331 @@
+package gocassa
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockSchemaAgreement(m *mock.Mock) {
+	version := gocql.TimeUUID()
+	m.On("Query", "SELECT schema_version FROM system.local", []interface{}(nil)).Return(
+		[]map[string]interface{}{{"schema_version": version}}, nil,
+	)
+	m.On("Query", "SELECT schema_version FROM system.peers", []interface{}(nil)).Return(
+		[]map[string]interface{}{{"schema_version": version}, {"schema_version": nil}}, nil,
+	)
+}
+
+func mockMigrationTables(m *mock.Mock) {
+	m.On(
+		"Execute",
+		"CREATE TABLE IF NOT EXISTS test.schema_migrations (version bigint,description varchar,applied_at timestamp,PRIMARY KEY (version))",
+		[]interface{}(nil),
+	).Return(nil)
+	m.On(
+		"Execute",
+		"CREATE TABLE IF NOT EXISTS test.schema_migrations_lock (id varchar,owner varchar,PRIMARY KEY (id))",
+		[]interface{}(nil),
+	).Return(nil)
+}
+
+func mockMigrationLock(m *mock.Mock) {
+	m.On(
+		"QueryCAS",
+		"INSERT INTO test.schema_migrations_lock (id,owner) VALUES (?,?) IF NOT EXISTS USING TTL 300",
+		mock.Anything,
+	).Return(map[string]interface{}{}, true, nil).Once()
+	m.On(
+		"QueryCAS",
+		"DELETE FROM test.schema_migrations_lock WHERE id = ? IF owner = ?",
+		mock.Anything,
+	).Return(map[string]interface{}{}, true, nil).Once()
+}
+
+func TestKeyspaceAwaitSchemaAgreement_timeout(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Query", "SELECT schema_version FROM system.local", []interface{}(nil)).Return(
+		[]map[string]interface{}{{"schema_version": gocql.TimeUUID()}}, nil,
+	)
+	m.On("Query", "SELECT schema_version FROM system.peers", []interface{}(nil)).Return(
+		[]map[string]interface{}{{"schema_version": gocql.TimeUUID()}}, nil,
+	)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	assert.NotNil(t, k.AwaitSchemaAgreement(0))
+}
+
+func TestMigratorUp(t *testing.T) {
+	m := &mock.Mock{}
+	mockSchemaAgreement(m)
+	mockMigrationTables(m)
+	mockMigrationLock(m)
+	m.On("Query", "SELECT version,description,applied_at FROM test.schema_migrations", []interface{}(nil)).Return(
+		[]map[string]interface{}{
+			{"version": int64(1), "description": "create users", "applied_at": time.Now()},
+		}, nil,
+	)
+	m.On(
+		"Execute",
+		"ALTER TABLE test.users ADD email varchar",
+		[]interface{}(nil),
+	).Return(nil)
+	m.On(
+		"Execute",
+		"INSERT INTO test.schema_migrations (version,description,applied_at) VALUES (?,?,?)",
+		mock.Anything,
+	).Return(nil).Twice()
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+
+	backfilled := false
+	err := NewMigrator(k, nil).Register(
+		Migration{
+			Version:     3,
+			Description: "backfill emails",
+			UpFunc: func(k *Keyspace) error {
+				backfilled = true
+				return nil
+			},
+		},
+		Migration{
+			Version:     1,
+			Description: "create users",
+			Up:          []string{"CREATE TABLE test.users (id varchar,PRIMARY KEY (id))"},
+		},
+		Migration{
+			Version:     2,
+			Description: "add email",
+			Up:          []string{"ALTER TABLE test.users ADD email varchar"},
+		},
+	).Up()
+
+	assert.Nil(t, err)
+	assert.True(t, backfilled)
+	m.AssertExpectations(t)
+	m.AssertNotCalled(t, "Execute", "CREATE TABLE test.users (id varchar,PRIMARY KEY (id))", []interface{}(nil))
+}
+
+func TestMigratorUp_failure(t *testing.T) {
+	m := &mock.Mock{}
+	mockSchemaAgreement(m)
+	mockMigrationTables(m)
+	mockMigrationLock(m)
+	m.On("Query", "SELECT version,description,applied_at FROM test.schema_migrations", []interface{}(nil)).Return(
+		[]map[string]interface{}{}, nil,
+	)
+	m.On(
+		"Execute",
+		"CREATE TABLE test.users (id varchar,PRIMARY KEY (id))",
+		[]interface{}(nil),
+	).Return(errors.New("syntax error"))
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	err := NewMigrator(k, nil).Register(Migration{
+		Version:     1,
+		Description: "create users",
+		Up:          []string{"CREATE TABLE test.users (id varchar,PRIMARY KEY (id))"},
+	}).Up()
+
+	assert.EqualError(t, err, "Migration 1 (create users) failed: syntax error")
+	m.AssertExpectations(t)
+}
+
+func TestMigratorUp_locked(t *testing.T) {
+	m := &mock.Mock{}
+	mockSchemaAgreement(m)
+	mockMigrationTables(m)
+	m.On(
+		"QueryCAS",
+		"INSERT INTO test.schema_migrations_lock (id,owner) VALUES (?,?) IF NOT EXISTS USING TTL 300",
+		mock.Anything,
+	).Return(map[string]interface{}{"id": "lock", "owner": "other"}, false, nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	err := NewMigrator(k, nil).Register(Migration{
+		Version: 1,
+		Up:      []string{"CREATE TABLE test.users (id varchar,PRIMARY KEY (id))"},
+	}).Up()
+
+	assert.Equal(t, MigrationLockError{Owner: "other"}, err)
+	m.AssertExpectations(t)
+}
+
+func TestMigratorDown(t *testing.T) {
+	m := &mock.Mock{}
+	mockSchemaAgreement(m)
+	mockMigrationTables(m)
+	mockMigrationLock(m)
+	m.On("Query", "SELECT version,description,applied_at FROM test.schema_migrations", []interface{}(nil)).Return(
+		[]map[string]interface{}{
+			{"version": int64(1), "description": "create users", "applied_at": time.Now()},
+			{"version": int64(2), "description": "add email", "applied_at": time.Now()},
+		}, nil,
+	)
+	m.On(
+		"Execute",
+		"ALTER TABLE test.users DROP email",
+		[]interface{}(nil),
+	).Return(nil)
+	m.On(
+		"Execute",
+		"DELETE FROM test.schema_migrations WHERE version = ?",
+		[]interface{}{int64(2)},
+	).Return(nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	err := NewMigrator(k, nil).Register(
+		Migration{
+			Version: 1,
+			Up:      []string{"CREATE TABLE test.users (id varchar,PRIMARY KEY (id))"},
+			Down:    []string{"DROP TABLE test.users"},
+		},
+		Migration{
+			Version: 2,
+			Up:      []string{"ALTER TABLE test.users ADD email varchar"},
+			Down:    []string{"ALTER TABLE test.users DROP email"},
+		},
+	).Down()
+
+	assert.Nil(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestMigratorUp_renewsLock(t *testing.T) {
+	m := &mock.Mock{}
+	mockSchemaAgreement(m)
+	mockMigrationTables(m)
+	m.On("QueryCAS", "INSERT INTO test.schema_migrations_lock (id,owner) VALUES (?,?) IF NOT EXISTS USING TTL 1", mock.Anything).
+		Return(map[string]interface{}{}, true, nil).Once()
+	m.On("QueryCAS", "UPDATE test.schema_migrations_lock USING TTL 1 SET owner = ? WHERE id = ? IF owner = ?", mock.Anything).
+		Return(map[string]interface{}{}, true, nil)
+	m.On("QueryCAS", "DELETE FROM test.schema_migrations_lock WHERE id = ? IF owner = ?", mock.Anything).
+		Return(map[string]interface{}{}, true, nil).Once()
+	m.On("Query", "SELECT version,description,applied_at FROM test.schema_migrations", []interface{}(nil)).Return(
+		[]map[string]interface{}{}, nil,
+	)
+	m.On("Execute", "INSERT INTO test.schema_migrations (version,description,applied_at) VALUES (?,?,?)", mock.Anything).Return(nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	err := NewMigrator(k, &MigratorOptions{LockTTL: 30 * time.Millisecond}).Register(Migration{
+		Version:     1,
+		Description: "slow backfill",
+		UpFunc: func(k *Keyspace) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		},
+	}).Up()
+
+	assert.Nil(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestMigratorUp_lockLost(t *testing.T) {
+	m := &mock.Mock{}
+	mockSchemaAgreement(m)
+	mockMigrationTables(m)
+	m.On("QueryCAS", "INSERT INTO test.schema_migrations_lock (id,owner) VALUES (?,?) IF NOT EXISTS USING TTL 1", mock.Anything).
+		Return(map[string]interface{}{}, true, nil).Once()
+	m.On("QueryCAS", "UPDATE test.schema_migrations_lock USING TTL 1 SET owner = ? WHERE id = ? IF owner = ?", mock.Anything).
+		Return(map[string]interface{}{"owner": "other"}, false, nil)
+	m.On("Query", "SELECT version,description,applied_at FROM test.schema_migrations", []interface{}(nil)).Return(
+		[]map[string]interface{}{}, nil,
+	)
+	m.On("Execute", "INSERT INTO test.schema_migrations (version,description,applied_at) VALUES (?,?,?)", mock.Anything).Return(nil)
+
+	ran := 0
+	slow := func(k *Keyspace) error {
+		ran++
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	err := NewMigrator(k, &MigratorOptions{LockTTL: 30 * time.Millisecond}).Register(
+		Migration{Version: 1, UpFunc: slow},
+		Migration{Version: 2, UpFunc: slow},
+	).Up()
+
+	assert.Equal(t, ErrMigrationLockLost, err)
+	assert.Equal(t, 1, ran, "no migration is started once the lock is lost")
+	m.AssertNotCalled(t, "QueryCAS", "DELETE FROM test.schema_migrations_lock WHERE id = ? IF owner = ?", mock.Anything)
+}
+
+func TestMigratorStatus_noHistory(t *testing.T) {
+	m := &mock.Mock{}
+	m.On(
+		"Query",
+		"SELECT table_name FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?",
+		[]interface{}{"test", "schema_migrations"},
+	).Return([]map[string]interface{}{}, nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	status, err := NewMigrator(k, nil).Register(Migration{
+		Version: 1,
+		Up:      []string{"CREATE TABLE test.users (id varchar,PRIMARY KEY (id))"},
+	}).Status()
+
+	assert.Nil(t, err)
+	if assert.Len(t, status, 1) {
+		assert.False(t, status[0].Applied)
+	}
+	m.AssertExpectations(t)
+	m.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+}
+
+func TestMigratorStatus(t *testing.T) {
+	appliedAt := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	m := &mock.Mock{}
+	m.On(
+		"Query",
+		"SELECT table_name FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?",
+		[]interface{}{"test", "schema_migrations"},
+	).Return([]map[string]interface{}{{"table_name": "schema_migrations"}}, nil)
+	m.On("Query", "SELECT version,description,applied_at FROM test.schema_migrations", []interface{}(nil)).Return(
+		[]map[string]interface{}{
+			{"version": int64(1), "description": "create users", "applied_at": appliedAt},
+			{"version": int64(0), "description": "removed", "applied_at": appliedAt},
+		}, nil,
+	)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	status, err := NewMigrator(k, nil).Register(
+		Migration{
+			Version:     1,
+			Description: "create users",
+			Up:          []string{"CREATE TABLE test.users (id varchar,PRIMARY KEY (id))"},
+		},
+		Migration{
+			Version:     2,
+			Description: "add email",
+			Up:          []string{"ALTER TABLE test.users ADD email varchar"},
+		},
+	).Status()
+
+	assert.Nil(t, err)
+	if assert.Len(t, status, 3) {
+		assert.Equal(t, int64(0), status[0].Migration.Version)
+		assert.True(t, status[0].Applied)
+		assert.Equal(t, int64(1), status[1].Migration.Version)
+		assert.True(t, status[1].Applied)
+		assert.Equal(t, appliedAt, status[1].AppliedAt)
+		assert.Equal(t, int64(2), status[2].Migration.Version)
+		assert.False(t, status[2].Applied)
+	}
+	m.AssertExpectations(t)
+}
+
+func TestMigrator_duplicateVersion(t *testing.T) {
+	k := NewKeyspace(NewMockExecutor(&mock.Mock{}), "test", nil)
+	err := NewMigrator(k, nil).Register(
+		Migration{Version: 1, Up: []string{"a"}},
+		Migration{Version: 1, Up: []string{"b"}},
+	).Up()
+
+	assert.EqualError(t, err, "Migration 1 is registered more than once")
+}
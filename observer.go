@@ -0,0 +1,135 @@
+package gocassa
+
+import (
+	"context"
+	"time"
+)
+
+// Operations reported in QueryEvent
+const (
+	OperationQuery   = "query"
+	OperationIter    = "iter"
+	OperationExecute = "execute"
+	OperationBatch   = "batch"
+)
+
+// QueryEvent describes a query, iteration or batch executed by the GoCQL query
+// executor. Fields describing the result are only set when the event is passed
+// to Observer.AfterQuery.
+type QueryEvent struct {
+	// Operation is one of OperationQuery, OperationIter, OperationExecute or
+	// OperationBatch
+	Operation string
+	// Query is the executed query, it is nil for batches
+	Query QueryGenerator
+	// Batch contains the queries of a batch
+	Batch []QueryGenerator
+	// Statement is the CQL statement of the query with the values of any
+	// sensitive columns redacted, it is empty for batches
+	Statement string
+	// Keyspace and Table are set for queries built with Query, they are only
+	// set for batches if every query in the batch uses the same table
+	Keyspace string
+	Table    string
+	// QueryType is "select", "insert", "update" or "delete" for queries built
+	// with Query, "raw" for raw queries and "batch" for batches
+	QueryType string
+	Start     time.Time
+
+	// Rows is the number of rows read, for iterators this is the number of
+	// rows scanned before the iterator was closed
+	Rows int
+	// Attempts is the number of times the query was sent to Cassandra,
+	// including any retries or speculative executions
+	Attempts int
+	Latency  time.Duration
+	Err      error
+}
+
+// An Observer is notified before and after each query, iteration and batch
+// executed by the GoCQL query executor, which can be used to collect metrics
+// or create trace spans.
+type Observer interface {
+	// BeforeQuery is called before the query is executed. The returned
+	// context is used to execute the query and passed to AfterQuery, which
+	// allows observers to start a trace span.
+	BeforeQuery(ctx context.Context, event QueryEvent) context.Context
+
+	// AfterQuery is called once the query has completed, or in the case of an
+	// iterator once it is closed.
+	AfterQuery(ctx context.Context, event QueryEvent)
+}
+
+// observeFunc completes the observation of a query started by observeQuery
+type observeFunc func(rows, attempts int, err error)
+
+func noopObserveFunc(rows, attempts int, err error) {}
+
+// observeQuery notifies the observer, if any, that the query is about to be
+// executed and returns the context which should be used to execute it along
+// with a function which must be called once it completes.
+func observeQuery(ctx context.Context, observer Observer, operation string, query QueryGenerator) (context.Context, observeFunc) {
+	if observer == nil {
+		return ctx, noopObserveFunc
+	}
+
+	// Use the redacted statement where possible as observers commonly export
+	// the statement to external tracing systems
+	var stmt string
+	if q, ok := query.(redactedStatementGenerator); ok {
+		stmt, _ = q.generateRedactedStatement()
+	} else {
+		stmt, _ = query.GenerateStatement()
+	}
+	event := QueryEvent{
+		Operation: operation,
+		Query:     query,
+		Statement: stmt,
+		QueryType: "raw",
+	}
+	if q, ok := query.(Query); ok {
+		event.Keyspace = q.table.keyspace.Name()
+		event.Table = q.table.Name()
+		event.QueryType = q.queryType.String()
+	}
+
+	return observe(ctx, observer, event)
+}
+
+// observeBatch is the same as observeQuery but for batches.
+func observeBatch(ctx context.Context, observer Observer, queries []QueryGenerator) (context.Context, observeFunc) {
+	if observer == nil {
+		return ctx, noopObserveFunc
+	}
+
+	event := QueryEvent{
+		Operation: OperationBatch,
+		Batch:     queries,
+		QueryType: "batch",
+	}
+	for i, query := range queries {
+		q, ok := query.(Query)
+		if !ok || (i > 0 && (q.table.keyspace.Name() != event.Keyspace || q.table.Name() != event.Table)) {
+			event.Keyspace, event.Table = "", ""
+			break
+		}
+		event.Keyspace = q.table.keyspace.Name()
+		event.Table = q.table.Name()
+	}
+
+	return observe(ctx, observer, event)
+}
+
+func observe(ctx context.Context, observer Observer, event QueryEvent) (context.Context, observeFunc) {
+	event.Start = time.Now()
+	ctx = observer.BeforeQuery(ctx, event)
+
+	return ctx, func(rows, attempts int, err error) {
+		event.Rows = rows
+		event.Attempts = attempts
+		event.Latency = time.Since(event.Start)
+		event.Err = err
+
+		observer.AfterQuery(ctx, event)
+	}
+}
@@ -0,0 +1,173 @@
+package gocassa
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+// histogram buckets used by NewMetricsCollector if none are given.
+var DefaultLatencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MetricsCollector is an Observer which records query latencies, errors, rows
+// and attempts per keyspace, table, operation and query type. The metrics are
+// exposed in the Prometheus text format by ServeHTTP so the collector can be
+// scraped directly without depending on a metrics library.
+type MetricsCollector struct {
+	buckets []float64
+
+	mtx    sync.Mutex
+	series map[metricsLabels]*metricsSeries
+}
+
+type metricsLabels struct {
+	keyspace  string
+	table     string
+	operation string
+	queryType string
+}
+
+type metricsSeries struct {
+	count        uint64
+	sum          float64
+	bucketCounts []uint64
+	errors       uint64
+	rows         uint64
+	attempts     uint64
+}
+
+// NewMetricsCollector creates a metrics collector using the given latency
+// histogram buckets, DefaultLatencyBuckets is used if buckets is empty.
+func NewMetricsCollector(buckets []float64) *MetricsCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	return &MetricsCollector{
+		buckets: buckets,
+		series:  map[metricsLabels]*metricsSeries{},
+	}
+}
+
+func (c *MetricsCollector) BeforeQuery(ctx context.Context, event QueryEvent) context.Context {
+	return ctx
+}
+
+func (c *MetricsCollector) AfterQuery(ctx context.Context, event QueryEvent) {
+	labels := metricsLabels{
+		keyspace:  event.Keyspace,
+		table:     event.Table,
+		operation: event.Operation,
+		queryType: event.QueryType,
+	}
+	latency := event.Latency.Seconds()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	s, ok := c.series[labels]
+	if !ok {
+		s = &metricsSeries{bucketCounts: make([]uint64, len(c.buckets))}
+		c.series[labels] = s
+	}
+
+	s.count++
+	s.sum += latency
+	for i, bound := range c.buckets {
+		if latency <= bound {
+			s.bucketCounts[i]++
+		}
+	}
+	if event.Err != nil {
+		s.errors++
+	}
+	s.rows += uint64(event.Rows)
+	s.attempts += uint64(event.Attempts)
+}
+
+// ServeHTTP writes the collected metrics in the Prometheus text format
+func (c *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes the collected metrics in the Prometheus text format to w
+func (c *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	labels := make([]metricsLabels, 0, len(c.series))
+	for l := range c.series {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		a, b := labels[i], labels[j]
+		if a.keyspace != b.keyspace {
+			return a.keyspace < b.keyspace
+		}
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		if a.operation != b.operation {
+			return a.operation < b.operation
+		}
+		return a.queryType < b.queryType
+	})
+
+	b := &strings.Builder{}
+
+	b.WriteString("# HELP gocassa_query_duration_seconds Latency of Cassandra queries.\n")
+	b.WriteString("# TYPE gocassa_query_duration_seconds histogram\n")
+	for _, l := range labels {
+		s := c.series[l]
+		for i, bound := range c.buckets {
+			fmt.Fprintf(b, "gocassa_query_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				l.String(), strconv.FormatFloat(bound, 'g', -1, 64), s.bucketCounts[i])
+		}
+		fmt.Fprintf(b, "gocassa_query_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l.String(), s.count)
+		fmt.Fprintf(b, "gocassa_query_duration_seconds_sum{%s} %s\n", l.String(), strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(b, "gocassa_query_duration_seconds_count{%s} %d\n", l.String(), s.count)
+	}
+
+	counters := []struct {
+		name, help string
+		value      func(s *metricsSeries) uint64
+	}{
+		{"gocassa_query_errors_total", "Number of Cassandra queries which returned an error.", func(s *metricsSeries) uint64 { return s.errors }},
+		{"gocassa_query_rows_total", "Number of rows read from Cassandra.", func(s *metricsSeries) uint64 { return s.rows }},
+		{"gocassa_query_attempts_total", "Number of attempts made to execute Cassandra queries, including retries.", func(s *metricsSeries) uint64 { return s.attempts }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(b, "# HELP %s %s\n", counter.name, counter.help)
+		fmt.Fprintf(b, "# TYPE %s counter\n", counter.name)
+		for _, l := range labels {
+			fmt.Fprintf(b, "%s{%s} %d\n", counter.name, l.String(), counter.value(c.series[l]))
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (l metricsLabels) String() string {
+	return fmt.Sprintf(`keyspace="%s",table="%s",operation="%s",type="%s"`,
+		escapeLabelValue(l.keyspace),
+		escapeLabelValue(l.table),
+		escapeLabelValue(l.operation),
+		escapeLabelValue(l.queryType),
+	)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
@@ -0,0 +1,166 @@
+package gocassa
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type observerKey struct{}
+
+type recordingObserver struct {
+	before []QueryEvent
+	after  []QueryEvent
+	ctxs   []context.Context
+}
+
+func (o *recordingObserver) BeforeQuery(ctx context.Context, event QueryEvent) context.Context {
+	o.before = append(o.before, event)
+	return context.WithValue(ctx, observerKey{}, len(o.before))
+}
+
+func (o *recordingObserver) AfterQuery(ctx context.Context, event QueryEvent) {
+	o.after = append(o.after, event)
+	o.ctxs = append(o.ctxs, ctx)
+}
+
+func TestObserveQuery(t *testing.T) {
+	k := NewKeyspace(NewMockExecutor(&mock.Mock{}), "test", nil)
+	tbl := NewTable(k, "test", SensitiveDocument{}, []string{"id"}, nil, nil)
+	q := tbl.Where(Eq("id", "1")).Read().Query
+
+	o := &recordingObserver{}
+	ctx, done := observeQuery(context.Background(), o, OperationQuery, q)
+	assert.Equal(t, 1, ctx.Value(observerKey{}))
+	if assert.Len(t, o.before, 1) {
+		assert.Equal(t, OperationQuery, o.before[0].Operation)
+		assert.Equal(t, "test", o.before[0].Keyspace)
+		assert.Equal(t, "test", o.before[0].Table)
+		assert.Equal(t, "select", o.before[0].QueryType)
+		assert.Equal(t, "SELECT * FROM test.test WHERE id = ?", o.before[0].Statement)
+	}
+	assert.Empty(t, o.after)
+
+	err := errors.New("Timeout")
+	done(2, 3, err)
+	if assert.Len(t, o.after, 1) {
+		assert.Equal(t, 2, o.after[0].Rows)
+		assert.Equal(t, 3, o.after[0].Attempts)
+		assert.Equal(t, err, o.after[0].Err)
+		assert.Equal(t, ctx, o.ctxs[0])
+	}
+}
+
+func TestObserveQuery_raw(t *testing.T) {
+	o := &recordingObserver{}
+	_, done := observeQuery(context.Background(), o, OperationExecute, NewRawQuery("TRUNCATE test.test", nil))
+	done(0, 1, nil)
+
+	if assert.Len(t, o.after, 1) {
+		assert.Equal(t, "raw", o.after[0].QueryType)
+		assert.Equal(t, "TRUNCATE test.test", o.after[0].Statement)
+		assert.Empty(t, o.after[0].Table)
+	}
+}
+
+func TestObserveQuery_noObserver(t *testing.T) {
+	ctx := context.Background()
+	observedCtx, done := observeQuery(ctx, nil, OperationQuery, NewRawQuery("SELECT * FROM test.test", nil))
+	assert.Equal(t, ctx, observedCtx)
+	done(0, 0, nil)
+}
+
+func TestObserveBatch(t *testing.T) {
+	k := NewKeyspace(NewMockExecutor(&mock.Mock{}), "test", nil)
+	tbl1 := NewTable(k, "test1", SensitiveDocument{}, []string{"id"}, nil, nil)
+	tbl2 := NewTable(k, "test2", SensitiveDocument{}, []string{"id"}, nil, nil)
+
+	o := &recordingObserver{}
+	_, done := observeBatch(context.Background(), o, []QueryGenerator{
+		tbl1.Set(SensitiveDocument{ID: "1"}).Query,
+		tbl1.Set(SensitiveDocument{ID: "2"}).Query,
+	})
+	done(0, 1, nil)
+	_, done = observeBatch(context.Background(), o, []QueryGenerator{
+		tbl1.Set(SensitiveDocument{ID: "1"}).Query,
+		tbl2.Set(SensitiveDocument{ID: "2"}).Query,
+	})
+	done(0, 1, nil)
+
+	if assert.Len(t, o.after, 2) {
+		assert.Equal(t, OperationBatch, o.after[0].Operation)
+		assert.Equal(t, "batch", o.after[0].QueryType)
+		assert.Equal(t, "test1", o.after[0].Table)
+		assert.Len(t, o.after[0].Batch, 2)
+
+		assert.Empty(t, o.after[1].Keyspace)
+		assert.Empty(t, o.after[1].Table)
+	}
+}
+
+func TestMetricsCollector(t *testing.T) {
+	c := NewMetricsCollector([]float64{0.01, 0.1})
+	c.AfterQuery(context.Background(), QueryEvent{
+		Operation: OperationQuery,
+		Keyspace:  "test",
+		Table:     "users",
+		QueryType: "select",
+		Rows:      3,
+		Attempts:  1,
+		Latency:   5 * time.Millisecond,
+	})
+	c.AfterQuery(context.Background(), QueryEvent{
+		Operation: OperationQuery,
+		Keyspace:  "test",
+		Table:     "users",
+		QueryType: "select",
+		Attempts:  2,
+		Latency:   50 * time.Millisecond,
+		Err:       errors.New("Timeout"),
+	})
+	c.AfterQuery(context.Background(), QueryEvent{
+		Operation: OperationExecute,
+		QueryType: "raw",
+		Attempts:  1,
+		Latency:   time.Second,
+	})
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	users := `keyspace="test",table="users",operation="query",type="select"`
+	raw := `keyspace="",table="",operation="execute",type="raw"`
+	for _, line := range []string{
+		"# TYPE gocassa_query_duration_seconds histogram",
+		`gocassa_query_duration_seconds_bucket{` + users + `,le="0.01"} 1`,
+		`gocassa_query_duration_seconds_bucket{` + users + `,le="0.1"} 2`,
+		`gocassa_query_duration_seconds_bucket{` + users + `,le="+Inf"} 2`,
+		`gocassa_query_duration_seconds_sum{` + users + `} 0.055`,
+		`gocassa_query_duration_seconds_count{` + users + `} 2`,
+		`gocassa_query_duration_seconds_bucket{` + raw + `,le="0.1"} 0`,
+		`gocassa_query_duration_seconds_bucket{` + raw + `,le="+Inf"} 1`,
+		"# TYPE gocassa_query_errors_total counter",
+		`gocassa_query_errors_total{` + users + `} 1`,
+		`gocassa_query_errors_total{` + raw + `} 0`,
+		`gocassa_query_rows_total{` + users + `} 3`,
+		`gocassa_query_attempts_total{` + users + `} 3`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+
+	// Series are sorted by their labels so the raw query is written first
+	assert.True(t, strings.Index(body, raw) < strings.Index(body, users))
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	assert.Equal(t, `a\"b\\c\nd`, escapeLabelValue("a\"b\\c\nd"))
+}
@@ -57,6 +57,28 @@ type QueryOptions struct {
 	SerialConsistency *gocql.SerialConsistency
 	// BatchType is used when executing a batch query
 	BatchType gocql.BatchType
+	// PageSize sets the number of rows fetched per page, if zero the default
+	// page size of the session is used
+	PageSize int
+	// PageState resumes a query from the page following the one the state was
+	// returned with, see Iter.PageState
+	PageState []byte
+	// Idempotent marks the query as safe to retry or execute speculatively. If
+	// nil, queries built with Query are considered idempotent unless they
+	// increment a counter or append or prepend to a list, raw queries are
+	// never considered idempotent. For batches it applies to every query in
+	// the batch.
+	Idempotent *bool
+	// RetryPolicy overrides the retry policy of the session. Only idempotent
+	// queries are retried.
+	RetryPolicy gocql.RetryPolicy
+	// SpeculativeExecution sets the speculative execution policy, it only
+	// applies to idempotent queries
+	SpeculativeExecution gocql.SpeculativeExecutionPolicy
+	// Timeout bounds the time taken to execute the query, including any
+	// retries and page fetches. If zero only the timeouts of the session and
+	// any context deadline apply.
+	Timeout time.Duration
 }
 
 type KeyspaceOptions struct {
@@ -70,4 +92,72 @@ type TableOptions struct {
 	CompactStorage bool
 	Orderings      []Ordering
 	Comment        string
+	// MultiRead sets the default options of MapTable.MultiReadContext
+	MultiRead *MultiReadOptions
+}
+
+// MultiReadOptions controls how MapTable.MultiReadContext reads partitions.
+type MultiReadOptions struct {
+	// FanOut issues a single partition read for each id concurrently instead
+	// of a single multi-partition IN query. This spreads the reads across
+	// coordinators rather than funnelling every partition through one.
+	FanOut bool
+	// Concurrency limits the number of reads in flight when FanOut is set,
+	// it defaults to 10
+	Concurrency int
+}
+
+type ExecutorOptions struct {
+	// Logger receives a log entry for every statement executed, if nil
+	// statements are not logged
+	Logger Logger
+	// LogLevel is the level statements are logged at, defaults to
+	// LogLevelDebug
+	LogLevel LogLevel
+	// LogValues includes the bound values of each statement in its log entry.
+	// Values of columns tagged as sensitive, for example `cql:",sensitive"`,
+	// are redacted. Values of raw queries are never redacted.
+	LogValues bool
+	// Observer is notified before and after every query, iteration and batch
+	Observer Observer
+	// AsyncConcurrency is the number of asynchronous queries, such as those
+	// started by RunnableQuery.Async, which can run at the same time. It
+	// defaults to 32.
+	AsyncConcurrency int
+}
+
+// ScanOptions configures a full table scan, see Table.ScanWithOptions
+type ScanOptions struct {
+	// Concurrency is the number of token ranges scanned at the same time, it
+	// defaults to 1
+	Concurrency int
+	// Ranges is the number of token ranges the token ring is split into, it
+	// defaults to four times the concurrency. Ignored if Checkpoints is set.
+	Ranges int
+	// PageSize is the number of rows fetched per query, if zero the default
+	// page size of the session is used
+	PageSize int
+	// Checkpoints resumes a previous scan from the checkpoints it reported,
+	// ranges which were completed are skipped
+	Checkpoints []ScanCheckpoint
+	// OnCheckpoint is called after each page of a range has been passed to the
+	// scan function. Calls are never concurrent.
+	OnCheckpoint func(checkpoint ScanCheckpoint)
+}
+
+// BatchPlanOptions controls how RunnableQueries.PlanBatches splits queries
+// into batches.
+type BatchPlanOptions struct {
+	// MaxStatements is the maximum number of statements in a batch, it
+	// defaults to 100
+	MaxStatements int
+	// MaxBytes is the maximum estimated size of a batch, it defaults to 5KiB
+	// which is the default batch_size_warn_threshold of Cassandra. Queries
+	// which are larger on their own are sent in a batch by themselves.
+	MaxBytes int
+	// Atomic sends every query in a single LOGGED batch so that they are
+	// applied atomically, even across tables and partitions. An error is
+	// returned if the batch exceeds MaxStatements or MaxBytes rather than
+	// splitting it.
+	Atomic bool
 }
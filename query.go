@@ -2,6 +2,7 @@ package gocassa
 
 import (
 	"bytes"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -20,6 +21,21 @@ const (
 	DeleteQueryType
 )
 
+func (t QueryType) String() string {
+	switch t {
+	case SelectQueryType:
+		return "select"
+	case InsertQueryType:
+		return "insert"
+	case UpdateQueryType:
+		return "update"
+	case DeleteQueryType:
+		return "delete"
+	default:
+		return ""
+	}
+}
+
 type QueryGenerator interface {
 	GenerateStatement() (stmt string, values []interface{})
 	WithOptions(options QueryOptions) QueryGenerator
@@ -61,6 +77,10 @@ type Query struct {
 	limit      int
 	values     map[string]interface{}
 	options    QueryOptions
+
+	// redact replaces the values of sensitive columns when generating the
+	// statement, see generateRedactedStatement
+	redact bool
 }
 
 func NewQuery(table *Table, queryType QueryType) Query {
@@ -120,6 +140,24 @@ func (q Query) GenerateStatement() (stmt string, values []interface{}) {
 	}
 }
 
+// isIdempotent reports whether the query can safely be retried. Reads and
+// deletes are always idempotent, writes are unless they use a modifier which
+// is not, such as a counter increment or list append.
+func (q Query) isIdempotent() bool {
+	for _, v := range q.values {
+		if mod, ok := v.(Modifier); ok && !mod.isIdempotent() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (q Query) generateRedactedStatement() (string, []interface{}) {
+	q.redact = true
+	return q.GenerateStatement()
+}
+
 func (q Query) generateSelectStatement() (string, []interface{}) {
 
 	buf := new(bytes.Buffer)
@@ -212,17 +250,13 @@ func (q Query) addSelectionsToStatement(buf *bytes.Buffer) []interface{} {
 func (q Query) addValueNamesToStatement(buf *bytes.Buffer) []interface{} {
 	values := []interface{}{}
 
-	// TODO: Sort values if required (for testing)
-
-	i := 0
-	for k, v := range q.values {
+	for i, k := range q.valueNames() {
 		if i > 0 {
 			buf.WriteString(",")
 		}
 
 		buf.WriteString(k)
-		values = append(values, v)
-		i++
+		values = append(values, q.bindValue(k, q.values[k]))
 	}
 
 	return values
@@ -244,26 +278,54 @@ func (q Query) addValuesToStatement(buf *bytes.Buffer) []interface{} {
 func (q Query) addAssignmentsToStatement(buf *bytes.Buffer) []interface{} {
 	values := []interface{}{}
 
-	i := 0
-	for k, v := range q.values {
+	for i, k := range q.valueNames() {
 		if i > 0 {
 			buf.WriteString(",")
 		}
 
+		v := q.values[k]
 		if mod, ok := v.(Modifier); ok {
-			stmt, vals := mod.generateCQL(k)
+			if q.redact && q.table.isSensitive(k) {
+				// Modifiers write their arguments into the statement
+				buf.WriteString(k + " = " + redactedValue)
+				continue
+			}
+
+			stmt, vals := mod.marshalArgs().generateCQL(k)
 			buf.WriteString(stmt)
 			values = append(values, vals...)
 		} else {
 			buf.WriteString(k + " = ?")
-			values = append(values, v)
+			values = append(values, q.bindValue(k, v))
 		}
-		i++
 	}
 
 	return values
 }
 
+// valueNames returns the names of the values sorted so that the generated
+// statement is always the same
+func (q Query) valueNames() []string {
+	names := make([]string, 0, len(q.values))
+	for k := range q.values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// bindValue returns the value which should be bound for the column, this is
+// the value converted by encoding.MarshalValue unless the statement is being
+// redacted and the column is sensitive
+func (q Query) bindValue(column string, v interface{}) interface{} {
+	if q.redact && q.table.isSensitive(column) {
+		return redactedValue
+	}
+
+	return encoding.MarshalValue(v)
+}
+
 func (q Query) addWhereToStatement(buf *bytes.Buffer) []interface{} {
 	values := []interface{}{}
 
@@ -276,9 +338,15 @@ func (q Query) addWhereToStatement(buf *bytes.Buffer) []interface{} {
 			cql, vals := r.generateCQL()
 			buf.WriteString(cql)
 			if r.relationType == relationTypeIN {
-				values = append(values, vals)
+				terms := make([]interface{}, len(vals))
+				for i, v := range vals {
+					terms[i] = encoding.MarshalValue(v)
+				}
+				values = append(values, q.bindValue(r.key, terms))
 			} else {
-				values = append(values, vals...)
+				for _, v := range vals {
+					values = append(values, q.bindValue(r.key, v))
+				}
 			}
 		}
 	}
@@ -354,7 +422,7 @@ func toMap(v interface{}) map[string]interface{} {
 
 	fields := make(map[string]interface{}, len(m))
 	for k, v := range m {
-		fields[strings.ToLower(k)] = v
+		fields[strings.ToLower(k)] = encoding.MarshalValue(v)
 	}
 
 	return fields
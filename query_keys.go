@@ -0,0 +1,111 @@
+package gocassa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dancannon/gocassa/encoding"
+	"github.com/gocql/gocql"
+)
+
+// insertKeys returns the primary key values of an insert query
+func insertKeys(q Query) (pk, ck []interface{}, err error) {
+	for _, k := range q.table.partitionKeys {
+		v, ok := q.values[k]
+		if !ok || v == nil {
+			return nil, nil, fmt.Errorf("Missing partition key column %s", k)
+		}
+		pk = append(pk, v)
+	}
+	for _, k := range q.table.clusteringColumns {
+		v, ok := q.values[k]
+		if !ok || v == nil {
+			return nil, nil, fmt.Errorf("Missing clustering column %s", k)
+		}
+		ck = append(ck, v)
+	}
+
+	return pk, ck, nil
+}
+
+// partitionKeys returns every partition key selected by the EQ and IN
+// relations of the query
+func partitionKeys(q Query) ([][]interface{}, error) {
+	return keyCombinations(q, q.table.partitionKeys, "partition key")
+}
+
+// clusteringKeys returns every set of clustering column values selected by
+// the EQ and IN relations of the query
+func clusteringKeys(q Query) ([][]interface{}, error) {
+	return keyCombinations(q, q.table.clusteringColumns, "clustering column")
+}
+
+func keyCombinations(q Query, columns []string, kind string) ([][]interface{}, error) {
+	keys := [][]interface{}{{}}
+	for _, column := range columns {
+		var terms []interface{}
+		for _, r := range q.relations {
+			if strings.ToLower(r.key) != column {
+				continue
+			}
+			switch r.relationType {
+			case relationTypeEQ, relationTypeIN:
+				terms = make([]interface{}, 0, len(r.terms))
+				for _, term := range r.terms {
+					terms = append(terms, encoding.MarshalValue(term))
+				}
+			}
+		}
+		if terms == nil {
+			return nil, fmt.Errorf("Missing %s %s", kind, column)
+		}
+
+		combined := make([][]interface{}, 0, len(keys)*len(terms))
+		for _, key := range keys {
+			for _, term := range terms {
+				combined = append(combined, append(append([]interface{}{}, key...), term))
+			}
+		}
+		keys = combined
+	}
+
+	return keys, nil
+}
+
+// columnType returns the type of the column, gocql.TypeCustom is used for
+// columns which are not part of the document
+func (t *Table) columnType(column string) gocql.TypeInfo {
+	typ := gocql.TypeCustom
+	for _, field := range t.documentFields {
+		if field.name == strings.ToLower(column) {
+			typ = field.cqlType
+		}
+	}
+
+	return gocql.NewNativeType(4, typ, "")
+}
+
+// keyBytes returns the value marshalled as a column of the given type so that
+// key values can be compared regardless of their Go type, such as a UUID
+// passed as a string or a time in another location. Values which can not be
+// marshalled are identified by their type and formatted value instead.
+func keyBytes(info gocql.TypeInfo, v interface{}) string {
+	data, err := gocql.Marshal(info, encoding.MarshalValue(v))
+	if err != nil {
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+
+	return string(data)
+}
+
+// primaryKeyBytes returns the values of the given key columns marshalled by
+// keyBytes, each prefixed by its length so that the result is unambiguous
+func (t *Table) primaryKeyBytes(columns []string, values []interface{}) string {
+	var key strings.Builder
+	for i, column := range columns {
+		b := keyBytes(t.columnType(column), values[i])
+		fmt.Fprintf(&key, "%d:%s", len(b), b)
+	}
+
+	return key.String()
+}
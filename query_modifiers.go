@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+
+	"github.com/dancannon/gocassa/encoding"
 )
 
 type Counter int
@@ -84,6 +86,37 @@ func CounterIncrement(value int) Modifier {
 	}
 }
 
+// isIdempotent reports whether applying the modifier more than once has the
+// same result as applying it once
+func (m Modifier) isIdempotent() bool {
+	switch m.op {
+	case modifierListPrepend, modifierListAppend, modifierCounterIncrement:
+		return false
+	default:
+		return true
+	}
+}
+
+// marshalArgs returns a copy of the modifier with its values converted by
+// encoding.MarshalValue
+func (m Modifier) marshalArgs() Modifier {
+	args := make([]interface{}, len(m.args))
+	for i, arg := range m.args {
+		if fields, ok := arg.(map[string]interface{}); ok && m.op == modifierMapSetFields {
+			marshalled := make(map[string]interface{}, len(fields))
+			for k, v := range fields {
+				marshalled[k] = encoding.MarshalValue(v)
+			}
+			args[i] = marshalled
+		} else {
+			args[i] = encoding.MarshalValue(arg)
+		}
+	}
+	m.args = args
+
+	return m
+}
+
 func (m Modifier) generateCQL(name string) (string, []interface{}) {
 	str := ""
 	vals := []interface{}{}
@@ -1,6 +1,7 @@
 package gocassa
 
 import (
+	"context"
 	"math/big"
 	"reflect"
 
@@ -43,7 +44,13 @@ func (q RunnableQuery) DeferScan(dest interface{}) RunnableQuery {
 // MapScan executes the query, copies the columns of the first selected
 // row into the map pointed at by dest and discards the rest.
 func (q RunnableQuery) ScanOne(dest interface{}) error {
-	v, err := q.Executor.QueryOne(q.Query)
+	return q.ScanOneContext(context.Background(), dest)
+}
+
+// ScanOneContext is the same as ScanOne except that the query is bound to the
+// given context.
+func (q RunnableQuery) ScanOneContext(ctx context.Context, dest interface{}) error {
+	v, err := q.Executor.QueryOneContext(ctx, q.Query)
 	if err != nil {
 		return err
 	}
@@ -70,7 +77,13 @@ func (q RunnableQuery) ScanCAS(dest interface{}) (applied bool, err error) {
 // MapScan executes the query, copies the columns of the each row into the slice
 // of maps pointed at by m and discards the rest.
 func (q RunnableQuery) Scan(dest interface{}) error {
-	v, err := q.Executor.Query(q.Query)
+	return q.ScanContext(context.Background(), dest)
+}
+
+// ScanContext is the same as Scan except that the query is bound to the given
+// context.
+func (q RunnableQuery) ScanContext(ctx context.Context, dest interface{}) error {
+	v, err := q.Executor.QueryContext(ctx, q.Query)
 	if err != nil {
 		return err
 	}
@@ -82,30 +95,136 @@ func (q RunnableQuery) Scan(dest interface{}) error {
 // pages are fetched, it is not the value of the total number of rows this iter
 // will return unless there is only a single page returned.
 func (q RunnableQuery) Iter() Iter {
-	return q.Executor.Iter(q.Query)
+	return q.IterContext(context.Background())
+}
+
+// IterContext is the same as Iter except that the query, and any subsequent
+// page fetches, are bound to the given context.
+func (q RunnableQuery) IterContext(ctx context.Context) Iter {
+	return q.Executor.IterContext(ctx, q.Query)
+}
+
+// Stream executes the query and sends each row to the returned row channel,
+// fetching further pages as the rows are received so that large results are
+// never held in memory at once. Rows of table queries are decoded into a
+// pointer to a new document, such as *Document, while rows of raw queries
+// and tables of maps are sent as map[string]interface{}. The row channel
+// buffers up to bufferSize rows.
+//
+// Both channels are closed once the query finishes. If it fails, or ctx is
+// done before every row has been received, the error is sent on the error
+// channel first. Callers which stop reading rows early should cancel ctx so
+// that the query is stopped.
+func (q RunnableQuery) Stream(ctx context.Context, bufferSize int) (<-chan interface{}, <-chan error) {
+	rows := make(chan interface{}, bufferSize)
+	errs := make(chan error, 1)
+
+	newRow := func() interface{} {
+		return &map[string]interface{}{}
+	}
+	if query, ok := q.Query.(Query); ok && query.table != nil {
+		newRow = query.table.newDocument
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(rows)
+
+		iter := q.Executor.IterContext(ctx, q.Query)
+		for {
+			row := newRow()
+			if !iter.Scan(row) {
+				break
+			}
+			if m, ok := row.(*map[string]interface{}); ok {
+				row = *m
+			}
+
+			select {
+			case rows <- row:
+			case <-ctx.Done():
+				iter.Close()
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		err := iter.Close()
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return rows, errs
+}
+
+// Page executes the query starting from the given page state, copies the rows
+// of that single page into the slice pointed at by dest and returns the page
+// state of the next page. The page state is an opaque cursor which can be
+// handed to clients and passed back to Page to continue the query, a nil page
+// state starts from the first page and an empty page state is returned once
+// there are no more pages. The page size can be set using the PageSize option.
+func (q RunnableQuery) Page(pageState []byte, dest interface{}) ([]byte, error) {
+	return q.PageContext(context.Background(), pageState, dest)
+}
+
+// PageContext is the same as Page except that the query is bound to the given
+// context.
+func (q RunnableQuery) PageContext(ctx context.Context, pageState []byte, dest interface{}) ([]byte, error) {
+	options := q.Query.Options()
+	options.PageState = pageState
+
+	iter := q.Executor.IterContext(ctx, q.Query.WithOptions(options))
+	nextPageState := iter.PageState()
+
+	// Only consume the rows of the current page so that the iterator does not
+	// fetch the next one
+	rows := make([]map[string]interface{}, 0, iter.NumRows())
+	for i, n := 0, iter.NumRows(); i < n; i++ {
+		row := map[string]interface{}{}
+		if !iter.Scan(&row) {
+			break
+		}
+		rows = append(rows, row)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return nextPageState, decodeResult(rows, dest)
 }
 
 func (q RunnableQuery) Execute() error {
+	return q.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is the same as Execute except that the query is bound to the
+// given context.
+func (q RunnableQuery) ExecuteContext(ctx context.Context) error {
 	if q.deferResult {
 		if q.deferOne {
-			return q.ScanOne(q.dest)
+			return q.ScanOneContext(ctx, q.dest)
 		} else {
-			return q.Scan(q.dest)
+			return q.ScanContext(ctx, q.dest)
 		}
 	}
 
-	return q.Executor.Execute(q.Query)
+	return q.Executor.ExecuteContext(ctx, q.Query)
 }
 
 type Iter interface {
 	// Scan consumes the next row of the iterator and copies the columns of the
-	// current row into the values pointed at by dest. Use nil as a dest value
-	// to skip the corresponding column. Scan might send additional queries
-	// to the database to retrieve the next set of rows if paging was enabled.
+	// current row into dest, which can be a pointer to a struct, a pointer to
+	// a pointer to a struct or a map. Scan might send additional queries to
+	// the database to retrieve the next set of rows if paging was enabled.
 	//
 	// Scan returns true if the row was successfully unmarshaled or false if the
-	// end of the result set was reached or if an error occurred. Close should
-	// be called afterwards to retrieve any potential errors.
+	// end of the result set was reached or if an error occurred, including an
+	// error decoding the row into dest. Close should be called afterwards to
+	// retrieve any potential errors.
 	Scan(dest interface{}) bool
 
 	// NumRows returns the number of rows in this pagination, it will update when new
@@ -117,6 +236,11 @@ type Iter interface {
 	// and the next page is available.
 	WillSwitchPage() bool
 
+	// PageState returns the current paging state for a query which can be used
+	// for subsequent queries to resume paging from this point, see the
+	// PageState query option.
+	PageState() []byte
+
 	// GetCustomPayload returns any parsed custom payload results if given in the
 	// response from Cassandra. Note that the result is not a copy.
 	GetCustomPayload() map[string][]byte
@@ -133,6 +257,7 @@ func decodeResult(v, dest interface{}) error {
 		TagName:          encoding.TagName,
 		DecodeHook: mapstructure.ComposeDecodeHookFunc(
 			decodeBigIntHook,
+			decodeUnmarshalerHook,
 		),
 	})
 	if err != nil {
@@ -142,6 +267,18 @@ func decodeResult(v, dest interface{}) error {
 	return dec.Decode(v)
 }
 
+// decodeUnmarshalerHook decodes values into types which implement
+// gocql.Unmarshaler, encoding.TextUnmarshaler or json.Unmarshaler, see
+// encoding.UnmarshalValue
+func decodeUnmarshalerHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	v, ok, err := encoding.UnmarshalValue(data, t)
+	if err != nil || !ok {
+		return data, err
+	}
+
+	return v, nil
+}
+
 func decodeBigIntHook(f reflect.Kind, t reflect.Kind, data interface{}) (interface{}, error) {
 	if f != reflect.Ptr {
 		return data, nil
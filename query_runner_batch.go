@@ -0,0 +1,280 @@
+package gocassa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	defaultBatchMaxStatements = 100
+	defaultBatchMaxBytes      = 5 * 1024
+)
+
+// ErrNoQueryExecutor is returned when executing queries which have no query
+// executor.
+var ErrNoQueryExecutor = errors.New("Query has no query executor")
+
+// Batch is a group of queries planned by RunnableQueries.PlanBatches which
+// are executed together as a single batch.
+type Batch struct {
+	// Indexes are the indexes of the queries in RunnableQueries.Queries
+	Indexes []int
+	Queries []RunnableQuery
+	// Options are the options of the batch, including the batch type
+	Options QueryOptions
+	// Size is the estimated size of the batch in bytes
+	Size int
+}
+
+// PlanBatches splits the queries into batches which can be executed without
+// tripping the batch size limits of Cassandra.
+//
+// Queries are grouped by table and partition key into UNLOGGED batches, or
+// COUNTER batches for counter tables, which are applied by a single replica
+// set without the overhead of the batch log. Each group is split further so
+// that no batch exceeds the statement count or estimated size in options.
+// Queries whose partition can not be determined, such as raw queries or
+// queries with an IN relation on the partition key, are put in a batch by
+// themselves and are never reordered with any other query. If options.Atomic
+// is set a single LOGGED batch is planned instead.
+//
+// The order of queries within a partition is preserved.
+func (qs RunnableQueries) PlanBatches(options *BatchPlanOptions) ([]Batch, error) {
+	if options == nil {
+		options = &BatchPlanOptions{}
+	}
+	maxStatements := options.MaxStatements
+	if maxStatements <= 0 {
+		maxStatements = defaultBatchMaxStatements
+	}
+	maxBytes := options.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultBatchMaxBytes
+	}
+
+	if len(qs.Queries) == 0 {
+		return nil, nil
+	}
+
+	if options.Atomic {
+		batch := qs.newBatch(gocql.LoggedBatch)
+		for i := range qs.Queries {
+			batch.add(i, qs.query(i))
+		}
+		if len(batch.Queries) > maxStatements || batch.Size > maxBytes {
+			return nil, fmt.Errorf("Atomic batch of %d statements (%d bytes) exceeds the batch limits", len(batch.Queries), batch.Size)
+		}
+		if err := batch.setCounterType(); err != nil {
+			return nil, err
+		}
+		return []Batch{batch}, nil
+	}
+
+	var batches []Batch
+	var keys []string
+	groups := map[string][]int{}
+
+	// flush splits the open groups into batches, in the order the groups
+	// first appeared
+	flush := func() {
+		for _, key := range keys {
+			batch := qs.newBatch(gocql.UnloggedBatch)
+			for _, i := range groups[key] {
+				q := qs.query(i)
+				size := estimateQuerySize(q.Query)
+				if len(batch.Queries) > 0 && (len(batch.Queries)+1 > maxStatements || batch.Size+size > maxBytes) {
+					batches = append(batches, batch)
+					batch = qs.newBatch(gocql.UnloggedBatch)
+				}
+				batch.add(i, q)
+			}
+			batches = append(batches, batch)
+		}
+		keys = nil
+		groups = map[string][]int{}
+	}
+
+	for i, q := range qs.Queries {
+		key, ok := batchPartitionKey(q.Query)
+		if !ok {
+			// The query may touch any partition so it acts as a barrier,
+			// queries before it are sent first and later ones after it
+			flush()
+			batch := qs.newBatch(gocql.UnloggedBatch)
+			batch.add(i, qs.query(i))
+			batches = append(batches, batch)
+			continue
+		}
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	flush()
+
+	for i := range batches {
+		if err := batches[i].setCounterType(); err != nil {
+			return nil, err
+		}
+	}
+
+	return batches, nil
+}
+
+// ExecuteBatches plans the batches using PlanBatches and executes each of
+// them in turn. Execution continues past failed batches, if any failed a
+// MultiBatchError is returned identifying which queries were not applied.
+func (qs RunnableQueries) ExecuteBatches(ctx context.Context, options *BatchPlanOptions) error {
+	batches, err := qs.PlanBatches(options)
+	if err != nil {
+		return err
+	}
+
+	var errs []BatchError
+	for _, batch := range batches {
+		queries := make([]QueryGenerator, len(batch.Queries))
+		for i, q := range batch.Queries {
+			queries[i] = q.Query
+		}
+
+		qe := batch.Queries[0].Executor
+		if qe == nil {
+			errs = append(errs, BatchError{Batch: batch, Err: ErrNoQueryExecutor})
+			continue
+		}
+		if err := qe.ExecuteBatchContext(ctx, queries, batch.Options); err != nil {
+			errs = append(errs, BatchError{Batch: batch, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return MultiBatchError{Errors: errs}
+	}
+	return nil
+}
+
+// newBatch creates an empty batch using the options of the queries. Counter
+// batches are kept as they can not be mixed with other batch types.
+func (qs RunnableQueries) newBatch(batchType gocql.BatchType) Batch {
+	options := qs.Options
+	if options.BatchType != gocql.CounterBatch {
+		options.BatchType = batchType
+	}
+
+	return Batch{Options: options}
+}
+
+// setCounterType switches the batch to a counter batch if it only contains
+// counter writes
+func (b *Batch) setCounterType() error {
+	queries := make([]QueryGenerator, len(b.Queries))
+	for i, q := range b.Queries {
+		queries[i] = q.Query
+	}
+
+	batchType, err := counterBatchType(queries, b.Options.BatchType)
+	if err != nil {
+		return err
+	}
+	b.Options.BatchType = batchType
+
+	return nil
+}
+
+func (b *Batch) add(i int, q RunnableQuery) {
+	b.Indexes = append(b.Indexes, i)
+	b.Queries = append(b.Queries, q)
+	b.Size += estimateQuerySize(q.Query)
+}
+
+// batchPartitionKey returns a key identifying the table and partition written
+// by the query, ok is false if it can not be determined
+func batchPartitionKey(query QueryGenerator) (key string, ok bool) {
+	q, ok := query.(Query)
+	if !ok || q.table == nil {
+		return "", false
+	}
+
+	var pk []interface{}
+	switch q.queryType {
+	case InsertQueryType:
+		var err error
+		if pk, _, err = insertKeys(q); err != nil {
+			return "", false
+		}
+	case UpdateQueryType, DeleteQueryType:
+		pks, err := partitionKeys(q)
+		if err != nil || len(pks) != 1 {
+			return "", false
+		}
+		pk = pks[0]
+	default:
+		return "", false
+	}
+
+	return q.table.keyspace.Name() + "." + q.table.Name() + ":" + q.table.primaryKeyBytes(q.table.partitionKeys, pk), true
+}
+
+// estimateQuerySize returns the approximate size of the query in a batch,
+// the length of the statement plus the encoded size of its values
+func estimateQuerySize(query QueryGenerator) int {
+	stmt, values := query.GenerateStatement()
+	size := len(stmt)
+	for _, v := range values {
+		size += estimateValueSize(v)
+	}
+
+	return size
+}
+
+func estimateValueSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case time.Time:
+		return 8
+	case gocql.UUID:
+		return 16
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return 1
+	case reflect.Int16, reflect.Uint16:
+		return 2
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4
+	case reflect.String:
+		return rv.Len()
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return 0
+		}
+		return estimateValueSize(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		size := 4
+		for i := 0; i < rv.Len(); i++ {
+			size += 4 + estimateValueSize(rv.Index(i).Interface())
+		}
+		return size
+	case reflect.Map:
+		size := 4
+		for _, k := range rv.MapKeys() {
+			size += 8 + estimateValueSize(k.Interface()) + estimateValueSize(rv.MapIndex(k).Interface())
+		}
+		return size
+	}
+
+	return 8
+}
@@ -1,6 +1,12 @@
 package gocassa
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gocql/gocql"
+)
 
 func MultiQuery(queries ...RunnableQuery) RunnableQueries {
 	return RunnableQueries{
@@ -27,21 +33,94 @@ func (qs RunnableQueries) Add(queries_ ...RunnableQuery) RunnableQueries {
 }
 
 func (qs RunnableQueries) Execute() error {
-	for _, q := range qs.Queries {
-		executor := qs.Executor
-		if executor == nil {
-			executor = q.Executor
+	for i := range qs.Queries {
+		if err := qs.query(i).Execute(); err != nil {
+			return err
 		}
+	}
 
-		if err := q.Execute(); err != nil {
-			return err
+	return nil
+}
+
+// ExecuteAll executes the queries one after the other like Execute except
+// that it continues past any failed queries. If any of the queries failed a
+// MultiError is returned identifying which ones.
+func (qs RunnableQueries) ExecuteAll(ctx context.Context) error {
+	var errs []QueryError
+	for i := range qs.Queries {
+		if err := qs.query(i).ExecuteContext(ctx); err != nil {
+			errs = append(errs, QueryError{Index: i, Err: err})
 		}
 	}
 
+	if len(errs) > 0 {
+		return MultiError{Errors: errs}
+	}
+	return nil
+}
+
+// ExecuteParallel executes the queries concurrently, with at most concurrency
+// queries in flight at once, and waits for all of them to finish. This is
+// useful for applying independent writes, such as to several denormalised
+// tables, where the order does not matter. If any of the queries failed, or
+// were not started because ctx was done, a MultiError is returned identifying
+// which ones.
+func (qs RunnableQueries) ExecuteParallel(ctx context.Context, concurrency int) error {
+	if concurrency <= 0 || concurrency > len(qs.Queries) {
+		concurrency = len(qs.Queries)
+	}
+
+	errs := make([]error, len(qs.Queries))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = qs.query(i).ExecuteContext(ctx)
+			}
+		}()
+	}
+
+	// Queries which were not started before ctx was done fail with its error
+	for i := range qs.Queries {
+		if ctx.Err() == nil {
+			select {
+			case indexes <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		for ; i < len(qs.Queries); i++ {
+			errs[i] = ctx.Err()
+		}
+		break
+	}
+	close(indexes)
+	wg.Wait()
+
+	var multiErr MultiError
+	for i, err := range errs {
+		if err != nil {
+			multiErr.Errors = append(multiErr.Errors, QueryError{Index: i, Err: err})
+		}
+	}
+
+	if len(multiErr.Errors) > 0 {
+		return multiErr
+	}
 	return nil
 }
 
 func (qs RunnableQueries) ExecuteBatch() error {
+	return qs.ExecuteBatchContext(context.Background())
+}
+
+// ExecuteBatchContext is the same as ExecuteBatch except that the batch is
+// bound to the given context.
+func (qs RunnableQueries) ExecuteBatchContext(ctx context.Context) error {
 	if len(qs.Queries) == 0 {
 		return nil
 	}
@@ -50,7 +129,15 @@ func (qs RunnableQueries) ExecuteBatch() error {
 	for i, q := range qs.Queries {
 		queries[i] = q.Query
 	}
-	return qs.queryExecutor().ExecuteBatch(queries, qs.Options)
+
+	options := qs.Options
+	batchType, err := counterBatchType(queries, options.BatchType)
+	if err != nil {
+		return err
+	}
+	options.BatchType = batchType
+
+	return qs.queryExecutor().ExecuteBatchContext(ctx, queries, options)
 }
 
 func (qs RunnableQueries) ExecuteBatchCAS() (result map[string]interface{}, iter Iter, applied bool, err error) {
@@ -66,6 +153,17 @@ func (qs RunnableQueries) ExecuteBatchCAS() (result map[string]interface{}, iter
 	return qs.queryExecutor().ExecuteBatchCAS(queries, qs.Options)
 }
 
+// query returns the query at index i, using the executor of the queries if
+// one is set
+func (qs *RunnableQueries) query(i int) RunnableQuery {
+	q := qs.Queries[i]
+	if qs.Executor != nil {
+		q.Executor = qs.Executor
+	}
+
+	return q
+}
+
 func (qs *RunnableQueries) queryExecutor() QueryExecutor {
 	if qs.Executor != nil {
 		return qs.Executor
@@ -77,3 +175,24 @@ func (qs *RunnableQueries) queryExecutor() QueryExecutor {
 
 	return nil
 }
+
+// counterBatchType returns gocql.CounterBatch if every query writes to a
+// counter table and batchType otherwise. Cassandra rejects batches which mix
+// counter and regular writes so an error is returned for those.
+func counterBatchType(queries []QueryGenerator, batchType gocql.BatchType) (gocql.BatchType, error) {
+	counters := 0
+	for _, query := range queries {
+		if q, ok := query.(Query); ok && q.table != nil && q.table.isCounter() {
+			counters++
+		}
+	}
+
+	switch counters {
+	case 0:
+		return batchType, nil
+	case len(queries):
+		return gocql.CounterBatch, nil
+	}
+
+	return batchType, fmt.Errorf("Counter writes can not be batched with other queries")
+}
@@ -0,0 +1,437 @@
+package gocassa
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIterScan_struct(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Iter", `SELECT * FROM test.test`, []interface{}{}).Return([]map[string]interface{}{
+		{"fielda": "a", "fieldb": "b"},
+		{"fielda": "c"},
+	}, nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	iter := tbl.List().Iter()
+
+	doc := Document{}
+	assert.True(t, iter.Scan(&doc))
+	assert.Equal(t, Document{FieldA: "a", FieldB: "b"}, doc)
+	assert.True(t, iter.Scan(&doc))
+	assert.Equal(t, Document{FieldA: "c"}, doc)
+	assert.False(t, iter.Scan(&doc))
+	assert.Nil(t, iter.Close())
+	m.AssertExpectations(t)
+}
+
+func TestIterScan_pointerAndMap(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Iter", `SELECT * FROM test.test`, []interface{}{}).Return([]map[string]interface{}{
+		{"fielda": "a"},
+		{"fielda": "b"},
+	}, nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	iter := tbl.List().Iter()
+
+	var doc *Document
+	assert.True(t, iter.Scan(&doc))
+	assert.Equal(t, &Document{FieldA: "a"}, doc)
+
+	row := map[string]interface{}{"stale": true}
+	assert.True(t, iter.Scan(row))
+	assert.Equal(t, map[string]interface{}{"fielda": "b"}, row)
+	assert.Nil(t, iter.Close())
+}
+
+func TestIterScan_conversion(t *testing.T) {
+	type Document struct {
+		Int     int
+		Uint8   uint8
+		Pointer *string
+		Counter Counter
+	}
+
+	m := &mock.Mock{}
+	m.On("Iter", `SELECT * FROM test.test`, []interface{}{}).Return([]map[string]interface{}{
+		{"int": int64(1), "uint8": "2", "pointer": "p", "counter": int64(3)},
+	}, nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"int", "uint8", "pointer"}, nil, nil)
+
+	iter := tbl.List().Iter()
+
+	doc := Document{}
+	assert.True(t, iter.Scan(&doc))
+	assert.Equal(t, 1, doc.Int)
+	assert.Equal(t, uint8(2), doc.Uint8)
+	if assert.NotNil(t, doc.Pointer) {
+		assert.Equal(t, "p", *doc.Pointer)
+	}
+	assert.Equal(t, Counter(3), doc.Counter)
+	assert.Nil(t, iter.Close())
+}
+
+// scaledCents is stored as whole units and unmarshalled into cents
+type scaledCents int64
+
+func (c *scaledCents) UnmarshalCQL(info gocql.TypeInfo, data []byte) error {
+	var v int64
+	if err := gocql.Unmarshal(info, data, &v); err != nil {
+		return err
+	}
+	*c = scaledCents(v * 100)
+	return nil
+}
+
+func TestDecodeRow_unmarshaler(t *testing.T) {
+	type Document struct {
+		Amount scaledCents
+	}
+
+	row := map[string]interface{}{"amount": int64(5)}
+
+	doc := Document{}
+	assert.Nil(t, decodeRow(row, &doc))
+	assert.Equal(t, scaledCents(500), doc.Amount)
+
+	doc = Document{}
+	assert.Nil(t, decodeResult(row, &doc))
+	assert.Equal(t, scaledCents(500), doc.Amount, "decodeResult and decodeRow agree")
+}
+
+func TestDecodeRow_numbers(t *testing.T) {
+	type Document struct {
+		Small  int8
+		Count  uint32
+		Amount int64
+		Ratio  float32
+	}
+
+	doc := Document{}
+	assert.Nil(t, decodeRow(map[string]interface{}{
+		"small":  int64(-128),
+		"count":  int64(7),
+		"amount": float64(1 << 40),
+		"ratio":  float64(0.5),
+	}, &doc))
+	assert.Equal(t, Document{Small: -128, Count: 7, Amount: 1 << 40, Ratio: 0.5}, doc)
+
+	for _, row := range []map[string]interface{}{
+		{"small": int64(128)},
+		{"count": int64(-1)},
+		{"count": uint64(1 << 32)},
+		{"amount": uint64(math.MaxUint64)},
+		{"amount": float64(1.5)},
+		{"amount": float64(1e19)},
+		{"ratio": float64(1e39)},
+	} {
+		assert.NotNil(t, decodeRow(row, &Document{}), "%v", row)
+	}
+}
+
+func TestIterScan_decodeError(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Iter", `SELECT * FROM test.test`, []interface{}{}).Return([]map[string]interface{}{
+		{"fielda": []int{1}},
+		{"fielda": "b"},
+	}, nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	iter := tbl.List().Iter()
+
+	doc := Document{}
+	assert.False(t, iter.Scan(&doc))
+	assert.False(t, iter.Scan(&doc))
+	assert.NotNil(t, iter.Close())
+}
+
+func TestRunnableQueryPage(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Iter", `SELECT * FROM test.test`, []interface{}{}).Return([]map[string]interface{}{
+		{"fielda": "a"},
+		{"fielda": "b"},
+	}, nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	docs := []Document{}
+	pageState, err := tbl.List().WithOptions(QueryOptions{PageSize: 2}).Page(nil, &docs)
+
+	assert.Nil(t, err)
+	assert.Nil(t, pageState)
+	assert.Equal(t, []Document{{FieldA: "a"}, {FieldA: "b"}}, docs)
+	m.AssertExpectations(t)
+}
+
+func TestRunnableQueriesExecuteParallel(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	errFailed := errors.New("Failed")
+	qe.ExpectUpdate("test").Where("fielda", "a")
+	qe.ExpectUpdate("test").Where("fielda", "b").ReturnError(errFailed)
+	qe.ExpectUpdate("test").Where("fielda", "c")
+
+	err := MultiQuery(
+		tbl.Set(Document{FieldA: "a"}),
+		tbl.Set(Document{FieldA: "b"}),
+		tbl.Set(Document{FieldA: "c"}),
+	).ExecuteParallel(context.Background(), 2)
+
+	multiErr, ok := err.(MultiError)
+	if assert.True(t, ok) && assert.Len(t, multiErr.Errors, 1) {
+		assert.Equal(t, 1, multiErr.Errors[0].Index)
+		assert.Equal(t, errFailed, multiErr.Errors[0].Err)
+	}
+	qe.AssertExpectations(t)
+}
+
+func TestRunnableQueriesExecuteParallel_cancelled(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := MultiQuery(
+		tbl.Set(Document{FieldA: "a"}),
+		tbl.Set(Document{FieldA: "b"}),
+	).ExecuteParallel(ctx, 1)
+
+	multiErr, ok := err.(MultiError)
+	if assert.True(t, ok) && assert.Len(t, multiErr.Errors, 2) {
+		for i, queryErr := range multiErr.Errors {
+			assert.Equal(t, QueryError{Index: i, Err: context.Canceled}, queryErr)
+		}
+	}
+	qe.AssertExpectations(t)
+}
+
+func TestRunnableQueriesExecuteAll(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	qe.InOrder()
+	qe.ExpectUpdate("test").Where("fielda", "a").ReturnError(errors.New("Failed"))
+	qe.ExpectUpdate("test").Where("fielda", "b")
+
+	qs := MultiQuery(tbl.Set(Document{FieldA: "a"}), tbl.Set(Document{FieldA: "b"}))
+	err := qs.ExecuteAll(context.Background())
+	assert.EqualError(t, err, "1 queries failed: Query 0 failed: Failed")
+	qe.AssertExpectations(t)
+
+	assert.Nil(t, MultiQuery().ExecuteAll(context.Background()))
+	assert.Nil(t, MultiQuery().ExecuteParallel(context.Background(), 0))
+}
+
+func TestRunnableQueriesPlanBatches(t *testing.T) {
+	k := NewKeyspace(NewDryRunExecutor(), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, []string{"fieldb"}, nil)
+	other := NewTable(k, "other", Document{}, []string{"fielda"}, nil, nil)
+
+	qs := MultiQuery(
+		tbl.Set(Document{FieldA: "a", FieldB: "1"}),
+		tbl.Set(Document{FieldA: "b", FieldB: "1"}),
+		tbl.Set(Document{FieldA: "a", FieldB: "2"}),
+		other.Set(Document{FieldA: "a"}),
+		tbl.Where(Eq("fielda", "a"), Eq("fieldb", "3")).Delete(),
+		tbl.Where(In("fielda", "a", "b")).Delete(),
+		tbl.Set(Document{FieldA: "a", FieldB: "4"}),
+	)
+
+	batches, err := qs.PlanBatches(&BatchPlanOptions{MaxStatements: 3})
+	assert.Nil(t, err)
+	indexes := [][]int{}
+	for _, batch := range batches {
+		assert.Equal(t, gocql.UnloggedBatch, batch.Options.BatchType)
+		indexes = append(indexes, batch.Indexes)
+	}
+	assert.Equal(t, [][]int{{0, 2, 4}, {1}, {3}, {5}, {6}}, indexes)
+
+	batches, err = qs.PlanBatches(&BatchPlanOptions{MaxBytes: 1})
+	assert.Nil(t, err)
+	assert.Len(t, batches, 7, "queries larger than the limit are sent on their own")
+
+	batches, err = qs.PlanBatches(&BatchPlanOptions{Atomic: true})
+	assert.Nil(t, err)
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, gocql.LoggedBatch, batches[0].Options.BatchType)
+		assert.Len(t, batches[0].Queries, 7)
+	}
+
+	_, err = qs.PlanBatches(&BatchPlanOptions{Atomic: true, MaxStatements: 2})
+	assert.NotNil(t, err)
+}
+
+func TestRunnableQueriesPlanBatches_barrier(t *testing.T) {
+	k := NewKeyspace(NewDryRunExecutor(), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, []string{"fieldb"}, nil)
+
+	batches, err := MultiQuery(
+		tbl.Set(Document{FieldA: "a", FieldB: "1"}),
+		tbl.Where(In("fielda", "a", "b")).Delete(),
+		tbl.Set(Document{FieldA: "a", FieldB: "2"}),
+		tbl.Set(Document{FieldA: "b", FieldB: "1"}),
+		tbl.Set(Document{FieldA: "a", FieldB: "3"}),
+	).PlanBatches(nil)
+	assert.Nil(t, err)
+
+	indexes := [][]int{}
+	for _, batch := range batches {
+		indexes = append(indexes, batch.Indexes)
+	}
+	assert.Equal(t, [][]int{{0}, {1}, {2, 4}, {3}}, indexes, "writes are not moved across a query with an unknown partition")
+}
+
+func TestRunnableQueriesExecuteBatches(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	qe.ExpectBatch(NewQueryExpectation("test", UpdateQueryType).Where("fielda", "a"))
+	qe.ExpectBatch(NewQueryExpectation("test", UpdateQueryType).Where("fielda", "b")).ReturnError(errors.New("Failed"))
+
+	err := MultiQuery(
+		tbl.Set(Document{FieldA: "a"}),
+		tbl.Set(Document{FieldA: "b"}),
+	).ExecuteBatches(context.Background(), nil)
+
+	multiErr, ok := err.(MultiBatchError)
+	if assert.True(t, ok) && assert.Len(t, multiErr.Errors, 1) {
+		assert.Equal(t, []int{1}, multiErr.Errors[0].Batch.Indexes)
+	}
+	qe.AssertExpectations(t)
+}
+
+func TestRunnableQueriesPlanBatches_keyTypes(t *testing.T) {
+	type event struct {
+		Created time.Time
+		Name    string
+	}
+
+	k := NewKeyspace(NewDryRunExecutor(), "test", nil)
+	tbl := NewTable(k, "events", event{}, []string{"created"}, nil, nil)
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	batches, err := MultiQuery(
+		tbl.Set(event{Created: created, Name: "a"}),
+		tbl.Set(event{Created: created.In(time.FixedZone("CET", 3600)), Name: "b"}),
+	).PlanBatches(nil)
+	assert.Nil(t, err)
+	if assert.Len(t, batches, 1, "equal times in different locations are the same partition") {
+		assert.Equal(t, []int{0, 1}, batches[0].Indexes)
+	}
+}
+
+func TestRunnableQueriesExecuteBatches_noExecutor(t *testing.T) {
+	k := NewKeyspace(nil, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	err := MultiQuery(tbl.Set(Document{FieldA: "a"})).ExecuteBatches(context.Background(), nil)
+	multiErr, ok := err.(MultiBatchError)
+	if assert.True(t, ok) && assert.Len(t, multiErr.Errors, 1) {
+		assert.Equal(t, ErrNoQueryExecutor, multiErr.Errors[0].Err)
+	}
+}
+
+func TestRunnableQueriesExecuteBatch_counter(t *testing.T) {
+	qe := NewDryRunExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	counters := NewCounterTable(k, "counters", CounterDocument{}, "id")
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	assert.Nil(t, MultiQuery(
+		counters.Increment("a", "views", 1),
+		counters.Increment("b", "views", 2),
+	).ExecuteBatch())
+	assert.Contains(t, qe.Script(), "BEGIN COUNTER BATCH")
+
+	err := MultiQuery(
+		counters.Increment("a", "views", 1),
+		tbl.Set(Document{FieldA: "a"}),
+	).ExecuteBatch()
+	assert.NotNil(t, err)
+
+	batches, err := MultiQuery(
+		counters.Increment("a", "views", 1),
+		tbl.Set(Document{FieldA: "a"}),
+	).PlanBatches(nil)
+	assert.Nil(t, err)
+	if assert.Len(t, batches, 2) {
+		assert.Equal(t, gocql.CounterBatch, batches[0].Options.BatchType)
+		assert.Equal(t, gocql.UnloggedBatch, batches[1].Options.BatchType)
+	}
+}
+
+func TestRunnableQueryStream(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, []string{"fieldb"}, nil)
+	for _, b := range []string{"1", "2", "3"} {
+		assert.Nil(t, tbl.Set(Document{FieldA: "a", FieldB: b}).Execute())
+	}
+
+	rows, errs := tbl.Where(Eq("fielda", "a")).Read().WithOptions(QueryOptions{PageSize: 2}).Stream(context.Background(), 1)
+	docs := []string{}
+	for row := range rows {
+		docs = append(docs, row.(*Document).FieldB)
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []string{"1", "2", "3"}, docs)
+
+	mapTbl := NewTable(k, "maps", map[string]interface{}{"id": ""}, []string{"id"}, nil, nil)
+	assert.Nil(t, mapTbl.Insert(map[string]interface{}{"id": "x"}).Execute())
+	rows, errs = mapTbl.List().Stream(context.Background(), 0)
+	for row := range rows {
+		assert.Equal(t, map[string]interface{}{"id": "x"}, row)
+	}
+	assert.Nil(t, <-errs)
+}
+
+func TestRunnableQueryStream_cancel(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, []string{"fieldb"}, nil)
+	for _, b := range []string{"1", "2", "3"} {
+		assert.Nil(t, tbl.Set(Document{FieldA: "a", FieldB: b}).Execute())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, errs := tbl.List().Stream(ctx, 0)
+	<-rows
+	cancel()
+
+	for range rows {
+	}
+	assert.Equal(t, context.Canceled, <-errs)
+}
+
+func TestRunnableQueryStream_error(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+	qe.ExpectSelect("test").ReturnError(errors.New("Failed"))
+
+	rows, errs := tbl.List().Stream(context.Background(), 0)
+	for range rows {
+	}
+	assert.EqualError(t, <-errs, "Failed")
+}
@@ -9,7 +9,7 @@ import (
 )
 
 func TestQuerySelect_order(t *testing.T) {
-	qe := NewMockExecutor(mock.Mock{})
+	qe := NewMockExecutor(&mock.Mock{})
 
 	k := NewKeyspace(qe, "test", nil)
 	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
@@ -24,7 +24,7 @@ func TestQuerySelect_order(t *testing.T) {
 }
 
 func TestQuerySelect_limit(t *testing.T) {
-	qe := NewMockExecutor(mock.Mock{})
+	qe := NewMockExecutor(&mock.Mock{})
 
 	k := NewKeyspace(qe, "test", nil)
 	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
@@ -37,7 +37,7 @@ func TestQuerySelect_limit(t *testing.T) {
 }
 
 func TestQuerySelect_allowFiltering(t *testing.T) {
-	qe := NewMockExecutor(mock.Mock{})
+	qe := NewMockExecutor(&mock.Mock{})
 
 	k := NewKeyspace(qe, "test", nil)
 	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
@@ -52,7 +52,7 @@ func TestQuerySelect_allowFiltering(t *testing.T) {
 }
 
 func TestQueryUpdate_timestamp(t *testing.T) {
-	qe := NewMockExecutor(mock.Mock{})
+	qe := NewMockExecutor(&mock.Mock{})
 
 	k := NewKeyspace(qe, "test", nil)
 	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
@@ -67,7 +67,7 @@ func TestQueryUpdate_timestamp(t *testing.T) {
 }
 
 func TestQueryUpdate_ttl(t *testing.T) {
-	qe := NewMockExecutor(mock.Mock{})
+	qe := NewMockExecutor(&mock.Mock{})
 
 	k := NewKeyspace(qe, "test", nil)
 	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
@@ -82,7 +82,7 @@ func TestQueryUpdate_ttl(t *testing.T) {
 }
 
 func TestQueryUpdate_timestampAndTTL(t *testing.T) {
-	qe := NewMockExecutor(mock.Mock{})
+	qe := NewMockExecutor(&mock.Mock{})
 
 	k := NewKeyspace(qe, "test", nil)
 	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
@@ -96,3 +96,63 @@ func TestQueryUpdate_timestampAndTTL(t *testing.T) {
 	assert.Equal(t, `UPDATE test.test SET a = ? USING TIMESTAMP 1451606400000 AND TTL 3600`, stmt)
 	assert.Equal(t, []interface{}{"a"}, values)
 }
+
+func TestQueryIsIdempotent(t *testing.T) {
+	qe := NewMockExecutor(&mock.Mock{})
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	assert.True(t, isIdempotent(NewQuery(tbl, SelectQueryType), QueryOptions{}))
+	assert.True(t, isIdempotent(NewQuery(tbl, DeleteQueryType), QueryOptions{}))
+	assert.True(t, isIdempotent(NewQuery(tbl, UpdateQueryType).Values(map[string]interface{}{
+		"fieldb": "b",
+		"fieldc": ListRemove("c"),
+		"fieldd": MapSetField("d", 1),
+	}), QueryOptions{}))
+
+	for _, mod := range []Modifier{ListAppend("b"), ListPrepend("b"), CounterIncrement(1)} {
+		q := NewQuery(tbl, UpdateQueryType).Values(map[string]interface{}{
+			"fieldb": mod,
+		})
+		assert.False(t, isIdempotent(q, QueryOptions{}))
+	}
+}
+
+func TestQueryIsIdempotent_options(t *testing.T) {
+	qe := NewMockExecutor(&mock.Mock{})
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+	yes, no := true, false
+
+	q := NewQuery(tbl, UpdateQueryType).Values(map[string]interface{}{
+		"fieldb": ListAppend("b"),
+	})
+	assert.True(t, isIdempotent(q, QueryOptions{Idempotent: &yes}))
+	assert.False(t, isIdempotent(NewQuery(tbl, SelectQueryType), QueryOptions{Idempotent: &no}))
+
+	raw := NewRawQuery("SELECT * FROM test.test", nil)
+	assert.False(t, isIdempotent(raw, QueryOptions{}))
+	assert.True(t, isIdempotent(raw, QueryOptions{Idempotent: &yes}))
+}
+
+func TestQueryMarshalValues(t *testing.T) {
+	k := NewKeyspace(NewMockExecutor(&mock.Mock{}), "test", nil)
+	tbl := NewTable(k, "accounts", account{}, []string{"id"}, nil, nil)
+
+	_, values := tbl.Where(Eq("status", accountActive), In("id", accountClosed, "b")).Read().Query.GenerateStatement()
+	assert.Equal(t, []interface{}{"active", []interface{}{"closed", "b"}}, values)
+
+	stmt, values := tbl.Where(Eq("id", "a")).Update(map[string]interface{}{
+		"status": accountClosed,
+	}).Query.GenerateStatement()
+	assert.Equal(t, `UPDATE test.accounts SET status = ? WHERE id = ?`, stmt)
+	assert.Equal(t, []interface{}{"closed", "a"}, values)
+
+	stmt, _ = tbl.Where(Eq("id", "a")).Update(map[string]interface{}{
+		"history": ListAppend(accountClosed),
+		"states":  MapSetField("a", accountActive),
+	}).Query.GenerateStatement()
+	assert.Equal(t, `UPDATE test.accounts SET history = history + ['closed'],states['a'] = 'active' WHERE id = ?`, stmt)
+}
@@ -0,0 +1,71 @@
+package gocassa
+
+// SchemaKind is the kind of an object in the schema of a keyspace. Objects are
+// created in the order of their kind, so that types exist before the tables
+// which use them and tables exist before their indexes and views, and are
+// dropped in the reverse order.
+type SchemaKind int
+
+const (
+	SchemaType SchemaKind = iota
+	SchemaTable
+	SchemaIndex
+	SchemaView
+)
+
+// A SchemaObject is an object in the schema of a keyspace, such as a table,
+// user defined type, secondary index or materialized view. Objects registered
+// with Keyspace.Register are included in Keyspace.CreateAll,
+// Keyspace.DropAll and Keyspace.SchemaCQL. Tables register themselves when
+// they are created with NewTable.
+type SchemaObject interface {
+	SchemaKind() SchemaKind
+	Name() string
+	// CreateStatement returns a CQL statement which creates the object if it
+	// does not already exist
+	CreateStatement() string
+	// DropStatement returns a CQL statement which deletes the object if it
+	// exists
+	DropStatement() string
+}
+
+type schemaObject struct {
+	kind   SchemaKind
+	name   string
+	create string
+	drop   string
+}
+
+// NewSchemaObject returns a schema object of the given kind which is created
+// and dropped with the given CQL statements, for example to register a user
+// defined type:
+//
+//	k.Register(gocassa.NewSchemaObject(
+//		gocassa.SchemaType, "address",
+//		"CREATE TYPE IF NOT EXISTS shop.address (street text,city text)",
+//		"DROP TYPE IF EXISTS shop.address",
+//	))
+func NewSchemaObject(kind SchemaKind, name, createStatement, dropStatement string) SchemaObject {
+	return schemaObject{
+		kind:   kind,
+		name:   name,
+		create: createStatement,
+		drop:   dropStatement,
+	}
+}
+
+func (o schemaObject) SchemaKind() SchemaKind {
+	return o.kind
+}
+
+func (o schemaObject) Name() string {
+	return o.name
+}
+
+func (o schemaObject) CreateStatement() string {
+	return o.create
+}
+
+func (o schemaObject) DropStatement() string {
+	return o.drop
+}
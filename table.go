@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"github.com/gocql/gocql"
 )
 
 // The Table type is the lowest level type included in the package and allows any
@@ -18,11 +20,16 @@ type Table struct {
 	clusteringColumns []string
 	documentValue     interface{}
 	documentFields    []tableField
+	sensitiveFields   map[string]bool
 	options           TableOptions
 }
 
 // NewTable creates a new table with the keys and fields specified, see the Table
-// type definition for more information.
+// type definition for more information. The table is registered with the
+// keyspace so that it is included in Keyspace.CreateAll and Keyspace.SchemaCQL.
+//
+// NewTable panics if the document mixes Counter fields with regular fields
+// outside of the primary key, as Cassandra does not allow such tables.
 func NewTable(
 	keyspace *Keyspace,
 	name string,
@@ -42,21 +49,55 @@ func NewTable(
 		clusteringColumns[i] = strings.ToLower(k)
 	}
 
-	return &Table{
+	fields := documentFields(documentValue)
+	if err := validateCounterColumns(fields, partitionKeys, clusteringColumns); err != nil {
+		panic(fmt.Sprintf("Invalid table %s: %v", name, err))
+	}
+
+	t := &Table{
 		keyspace:          keyspace,
 		name:              name,
 		partitionKeys:     partitionKeys,
 		clusteringColumns: clusteringColumns,
 		documentValue:     documentValue,
-		documentFields:    documentFields(documentValue),
+		documentFields:    fields,
+		sensitiveFields:   sensitiveFields(documentValue),
 		options:           *options,
 	}
+	if keyspace != nil {
+		keyspace.register(t)
+	}
+
+	return t
 }
 
 func (t *Table) Name() string {
 	return t.name
 }
 
+// SchemaKind returns SchemaTable, see SchemaObject
+func (t *Table) SchemaKind() SchemaKind {
+	return SchemaTable
+}
+
+// isCounter returns true if the columns outside of the primary key are
+// counters, writes to such tables can only be batched in counter batches.
+func (t *Table) isCounter() bool {
+	for _, field := range t.documentFields {
+		if field.cqlType == gocql.TypeCounter {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSensitive returns true if the column was tagged as sensitive in the
+// document struct and so its values should not be logged.
+func (t *Table) isSensitive(column string) bool {
+	return t.sensitiveFields[strings.ToLower(column)]
+}
+
 func (t *Table) WithOptions(options TableOptions) *Table {
 	t.options = options
 	return t
@@ -132,6 +173,34 @@ func (t *Table) Drop() error {
 	return t.keyspace.QueryExecutor().Execute(NewRawQuery(t.DropStatement(), nil))
 }
 
+// TruncateStatement returns a CQL which will remove all rows from the current
+// table.
+func (t *Table) TruncateStatement() string {
+	return fmt.Sprintf("TRUNCATE TABLE %s.%s", t.keyspace.Name(), t.Name())
+}
+
+// Truncate attempts to remove all rows from the current table.
+func (t *Table) Truncate() error {
+	return t.keyspace.QueryExecutor().Execute(NewRawQuery(t.TruncateStatement(), nil))
+}
+
+// Recreate drops the current table if it exists and creates it again, waiting
+// for schema agreement after each step so that the table can be used as soon
+// as Recreate returns.
+func (t *Table) Recreate() error {
+	if err := t.Drop(); err != nil {
+		return err
+	}
+	if err := t.keyspace.AwaitSchemaAgreement(DefaultSchemaAgreementTimeout); err != nil {
+		return err
+	}
+	if err := t.Create(); err != nil {
+		return err
+	}
+
+	return t.keyspace.AwaitSchemaAgreement(DefaultSchemaAgreementTimeout)
+}
+
 func (t *Table) Set(v interface{}) RunnableQuery {
 	fields := transformFields(toMap(v))
 	updateFields := removeFields(fields, append(t.partitionKeys, t.clusteringColumns...))
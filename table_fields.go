@@ -39,15 +39,47 @@ func documentFields(v interface{}) []tableField {
 		m = encoding.StructToMap(v)
 	}
 
+	// Column types set with the type tag option, or text for fields which
+	// are marshalled as text
+	tagTypes := map[string]string{}
+	if t := reflect.TypeOf(v); t != nil {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			for _, field := range encoding.Fields(t) {
+				switch {
+				case field.CQLType != "":
+					tagTypes[field.Name] = field.CQLType
+				case encoding.IsTextMarshaler(field.Type):
+					tagTypes[field.Name] = gocql.TypeVarchar.String()
+				}
+			}
+		}
+	}
+
 	tableFields := make([]tableField, 0, len(m))
 	for k, v := range m {
 		fieldType := reflect.TypeOf(v)
-		tableFields = append(tableFields, tableField{
+
+		// Nil pointers, such as those of text marshalers, are typed by the
+		// value they point to
+		if fieldType != nil && fieldType.Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+			fieldType = fieldType.Elem()
+			v = reflect.Zero(fieldType).Interface()
+		}
+
+		field := tableField{
 			name:      strings.ToLower(k),
 			fieldType: fieldType,
 			cqlType:   cqlType(v),
 			typeName:  typeName(v, fieldType),
-		})
+		}
+		if typ, ok := tagTypes[k]; ok {
+			field.typeName = typ
+			field.cqlType = cqlTypeByName(typ)
+		}
+		tableFields = append(tableFields, field)
 	}
 
 	// Ensure resulting fields slice is sorted
@@ -56,6 +88,27 @@ func documentFields(v interface{}) []tableField {
 	return tableFields
 }
 
+// sensitiveFields returns the names of the fields of the document struct which
+// are tagged as sensitive.
+func sensitiveFields(v interface{}) map[string]bool {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := map[string]bool{}
+	for _, field := range encoding.Fields(t) {
+		if field.Sensitive {
+			fields[strings.ToLower(field.Name)] = true
+		}
+	}
+
+	return fields
+}
+
 func typeName(v interface{}, t reflect.Type) string {
 	isByteSlice := t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
 	if !isByteSlice {
@@ -121,3 +174,84 @@ func cqlType(v interface{}) gocql.Type {
 
 	return gocql.TypeCustom
 }
+
+// cqlTypeByName returns the type with the given CQL name, collections and
+// unrecognised types are returned as gocql.TypeCustom
+func cqlTypeByName(name string) gocql.Type {
+	switch strings.ToLower(name) {
+	case "ascii":
+		return gocql.TypeAscii
+	case "bigint":
+		return gocql.TypeBigInt
+	case "blob":
+		return gocql.TypeBlob
+	case "boolean":
+		return gocql.TypeBoolean
+	case "counter":
+		return gocql.TypeCounter
+	case "decimal":
+		return gocql.TypeDecimal
+	case "double":
+		return gocql.TypeDouble
+	case "float":
+		return gocql.TypeFloat
+	case "int":
+		return gocql.TypeInt
+	case "smallint":
+		return gocql.TypeSmallInt
+	case "tinyint":
+		return gocql.TypeTinyInt
+	case "text", "varchar":
+		return gocql.TypeVarchar
+	case "timestamp":
+		return gocql.TypeTimestamp
+	case "uuid":
+		return gocql.TypeUUID
+	case "timeuuid":
+		return gocql.TypeTimeUUID
+	case "varint":
+		return gocql.TypeVarint
+	case "inet":
+		return gocql.TypeInet
+	case "date":
+		return gocql.TypeDate
+	case "time":
+		return gocql.TypeTime
+	case "duration":
+		return gocql.TypeDuration
+	}
+
+	return gocql.TypeCustom
+}
+
+// validateCounterColumns returns an error if the fields mix counter and
+// regular columns, which Cassandra rejects. In a counter table every column
+// outside of the primary key must be a counter and the primary key can not
+// contain counters.
+func validateCounterColumns(fields []tableField, partitionKeys, clusteringColumns []string) error {
+	keys := map[string]bool{}
+	for _, k := range append(append([]string{}, partitionKeys...), clusteringColumns...) {
+		keys[k] = true
+	}
+
+	var counter, regular string
+	for _, field := range fields {
+		switch {
+		case field.cqlType == gocql.TypeCounter && keys[field.name]:
+			return fmt.Errorf("Counter column %s can not be part of the primary key", field.name)
+		case field.cqlType == gocql.TypeCounter:
+			if counter == "" {
+				counter = field.name
+			}
+		case !keys[field.name]:
+			if regular == "" {
+				regular = field.name
+			}
+		}
+	}
+	if counter != "" && regular != "" {
+		return fmt.Errorf("Counter column %s can not be mixed with regular column %s", counter, regular)
+	}
+
+	return nil
+}
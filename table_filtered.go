@@ -1,5 +1,11 @@
 package gocassa
 
+import "errors"
+
+// ErrFilteredTruncate is returned when truncating a FilteredTable, as a
+// truncate can not be limited to the rows matching the relations.
+var ErrFilteredTruncate = errors.New("Cannot truncate a filtered table, use Delete to remove the matching rows")
+
 // The FilteredTable type represents a Table that has been filtered by some
 // relations (a relation is a 'WHERE' condition such as '=' or 'IN'). To create
 // a FilteredTable the Where function in the Table type can be used.
@@ -71,3 +77,9 @@ func (t *FilteredTable) Where(relations ...Relation) *FilteredTable {
 
 	return t
 }
+
+// Truncate returns ErrFilteredTruncate rather than removing every row of the
+// underlying table.
+func (t *FilteredTable) Truncate() error {
+	return ErrFilteredTruncate
+}
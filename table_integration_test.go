@@ -18,8 +18,7 @@ func TestIntegrationTableSinglePartitionKey(t *testing.T) {
 
 	tbl := NewTable(keyspace, "table_single_partition", Document{}, []string{"fielda"}, nil, nil)
 
-	assert.Nil(t, tbl.Drop())
-	assert.Nil(t, tbl.Create())
+	assert.Nil(t, tbl.Recreate())
 
 	t.Run("Set", func(t *testing.T) {
 		assert.Nil(t, tbl.Set(Document{
@@ -66,6 +65,25 @@ func TestIntegrationTableSinglePartitionKey(t *testing.T) {
 		}
 	})
 
+	t.Run("Page", func(t *testing.T) {
+		query := tbl.List().WithOptions(QueryOptions{PageSize: 1})
+
+		docs := []Document{}
+		pageState, err := query.Page(nil, &docs)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, pageState)
+		if assert.Len(t, docs, 1) {
+			assert.Equal(t, "a", docs[0].FieldA)
+		}
+
+		docs = []Document{}
+		_, err = query.Page(pageState, &docs)
+		assert.Nil(t, err)
+		if assert.Len(t, docs, 1) {
+			assert.Equal(t, "e", docs[0].FieldA)
+		}
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		err := tbl.Where(Eq("fielda", "a")).Delete().Execute()
 		assert.Nil(t, err)
@@ -88,8 +106,7 @@ func TestIntegrationModifiers(t *testing.T) {
 
 	tbl := NewTable(keyspace, "table_modifiers", Document{}, []string{"ID"}, nil, nil)
 
-	assert.Nil(t, tbl.Drop())
-	assert.Nil(t, tbl.Create())
+	assert.Nil(t, tbl.Recreate())
 
 	t.Run("Set", func(t *testing.T) {
 		assert.Nil(t, tbl.Set(Document{
@@ -155,8 +172,7 @@ func TestIntegrationMultiQuery(t *testing.T) {
 
 	tbl := NewTable(keyspace, "table_multi", Document{}, []string{"ID"}, nil, nil)
 
-	assert.Nil(t, tbl.Drop())
-	assert.Nil(t, tbl.Create())
+	assert.Nil(t, tbl.Recreate())
 
 	t.Run("Execute", func(t *testing.T) {
 		q := MultiQuery()
@@ -0,0 +1,199 @@
+package gocassa
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ScanCheckpoint records the progress of a scan through a single token range,
+// the range covers the tokens greater than Start and less than or equal to
+// End.
+type ScanCheckpoint struct {
+	Start int64
+	End   int64
+	// PageState is the page state of the next page of the range, it is nil
+	// if no pages have been read
+	PageState []byte
+	// Done is true once every row in the range has been passed to the scan
+	// function
+	Done bool
+}
+
+// Scan reads every row of the table by splitting the Murmur3 token ring into
+// ranges which are queried in parallel, using at most concurrency queries at
+// a time. Each row is decoded into a new document and passed to fn as a
+// pointer, or as a map if the table document is a map. Rows are not returned
+// in any particular order and fn may be called concurrently.
+//
+// The scan stops at the first error returned by fn or a query, and covers the
+// whole table regardless of any relations. See ScanWithOptions to control
+// paging or resume a previous scan.
+func (t *Table) Scan(ctx context.Context, concurrency int, fn func(doc interface{}) error) error {
+	return t.ScanWithOptions(ctx, &ScanOptions{Concurrency: concurrency}, fn)
+}
+
+// ScanWithOptions is the same as Scan but allows the scan to be configured,
+// see ScanOptions.
+func (t *Table) ScanWithOptions(ctx context.Context, options *ScanOptions, fn func(doc interface{}) error) error {
+	if options == nil {
+		options = &ScanOptions{}
+	}
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	checkpoints := options.Checkpoints
+	if len(checkpoints) == 0 {
+		ranges := options.Ranges
+		if ranges < 1 {
+			ranges = concurrency * 4
+		}
+		checkpoints = tokenRanges(ranges)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		firstErr error
+	)
+	checkpointed := func(checkpoint ScanCheckpoint) {
+		if options.OnCheckpoint == nil {
+			return
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		options.OnCheckpoint(checkpoint)
+	}
+
+	ranges := make(chan ScanCheckpoint)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for checkpoint := range ranges {
+				if err := t.scanRange(ctx, checkpoint, options.PageSize, fn, checkpointed); err != nil {
+					mtx.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mtx.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+
+send:
+	for _, checkpoint := range checkpoints {
+		if checkpoint.Done {
+			continue
+		}
+
+		select {
+		case ranges <- checkpoint:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(ranges)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
+// scanRange reads the rows of a single token range a page at a time starting
+// from the checkpoint
+func (t *Table) scanRange(
+	ctx context.Context,
+	checkpoint ScanCheckpoint,
+	pageSize int,
+	fn func(doc interface{}) error,
+	checkpointed func(ScanCheckpoint),
+) error {
+	token := "token(" + strings.Join(t.partitionKeys, ",") + ")"
+	q := NewQuery(t, SelectQueryType).Where(
+		GT(token, checkpoint.Start),
+		LTE(token, checkpoint.End),
+	)
+
+	for !checkpoint.Done {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		iter := t.keyspace.QueryExecutor().IterContext(ctx, q.WithOptions(QueryOptions{
+			PageSize:  pageSize,
+			PageState: checkpoint.PageState,
+		}))
+		pageState := iter.PageState()
+
+		// Only consume the rows of the current page so that the checkpoint
+		// matches the rows passed to fn
+		for i, n := 0, iter.NumRows(); i < n; i++ {
+			doc := t.newDocument()
+			if !iter.Scan(doc) {
+				break
+			}
+			if m, ok := doc.(*map[string]interface{}); ok {
+				doc = *m
+			}
+			if err := fn(doc); err != nil {
+				iter.Close()
+				return err
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+
+		checkpoint.PageState = pageState
+		checkpoint.Done = len(pageState) == 0
+		checkpointed(checkpoint)
+	}
+
+	return nil
+}
+
+// newDocument returns a pointer to a new value of the document type
+func (t *Table) newDocument() interface{} {
+	typ := reflect.TypeOf(t.documentValue)
+	if typ == nil {
+		return &map[string]interface{}{}
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	return reflect.New(typ).Interface()
+}
+
+// tokenRanges splits the Murmur3 token ring into n ranges of roughly equal
+// size. The ranges exclude the minimum token, which Murmur3 never assigns.
+func tokenRanges(n int) []ScanCheckpoint {
+	span := uint64(math.MaxUint64) / uint64(n)
+	ranges := make([]ScanCheckpoint, n)
+	start := int64(math.MinInt64)
+	for i := range ranges {
+		end := int64(uint64(start) + span)
+		if i == n-1 {
+			end = math.MaxInt64
+		}
+		ranges[i] = ScanCheckpoint{Start: start, End: end}
+		start = end
+	}
+
+	return ranges
+}
@@ -0,0 +1,109 @@
+package gocassa
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenRanges(t *testing.T) {
+	ranges := tokenRanges(4)
+	if assert.Len(t, ranges, 4) {
+		assert.Equal(t, int64(math.MinInt64), ranges[0].Start)
+		assert.Equal(t, int64(math.MaxInt64), ranges[3].End)
+		for i := 1; i < len(ranges); i++ {
+			assert.Equal(t, ranges[i-1].End, ranges[i].Start)
+			assert.True(t, ranges[i].Start < ranges[i].End)
+		}
+	}
+
+	ranges = tokenRanges(2)
+	assert.Equal(t, []ScanCheckpoint{
+		{Start: math.MinInt64, End: -1},
+		{Start: -1, End: math.MaxInt64},
+	}, ranges)
+
+	ranges = tokenRanges(1)
+	assert.Equal(t, []ScanCheckpoint{{Start: math.MinInt64, End: math.MaxInt64}}, ranges)
+}
+
+func TestTableScan(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	for i, r := range tokenRanges(2) {
+		qe.ExpectSelect("test").
+			WhereRelation(GT("token(fielda)", r.Start)).
+			WhereRelation(LTE("token(fielda)", r.End)).
+			Return([]map[string]interface{}{
+				{"fielda": string(rune('a' + i*2))},
+				{"fielda": string(rune('b' + i*2))},
+			})
+	}
+
+	var mtx sync.Mutex
+	docs := []string{}
+	checkpoints := []ScanCheckpoint{}
+	err := tbl.ScanWithOptions(context.Background(), &ScanOptions{
+		Concurrency: 2,
+		Ranges:      2,
+		OnCheckpoint: func(checkpoint ScanCheckpoint) {
+			checkpoints = append(checkpoints, checkpoint)
+		},
+	}, func(doc interface{}) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		docs = append(docs, doc.(*Document).FieldA)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	sort.Strings(docs)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, docs)
+	if assert.Len(t, checkpoints, 2) {
+		assert.True(t, checkpoints[0].Done)
+		assert.True(t, checkpoints[1].Done)
+	}
+	qe.AssertExpectations(t)
+}
+
+func TestTableScan_resume(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	ranges := tokenRanges(2)
+	ranges[0].Done = true
+	qe.ExpectSelect("test").
+		WhereRelation(GT("token(fielda)", ranges[1].Start)).
+		Return([]map[string]interface{}{{"fielda": "c"}})
+
+	count := 0
+	err := tbl.ScanWithOptions(context.Background(), &ScanOptions{Checkpoints: ranges}, func(doc interface{}) error {
+		count++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	qe.AssertExpectations(t)
+}
+
+func TestTableScan_error(t *testing.T) {
+	qe := NewExpectExecutor()
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	qe.ExpectSelect("test").Return([]map[string]interface{}{{"fielda": "a"}}).Times(4)
+
+	errStop := errors.New("Stop")
+	err := tbl.Scan(context.Background(), 1, func(doc interface{}) error {
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+}
@@ -1,6 +1,9 @@
 package gocassa
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -20,7 +23,12 @@ type TypeDocument struct {
 	FieldH time.Time  // timestamp
 	FieldI gocql.UUID // uuid
 	FieldJ []byte     // blob
-	FieldK Counter    // counter
+}
+
+type CounterDocument struct {
+	ID     string
+	Views  Counter
+	Clicks Counter
 }
 
 type Document struct {
@@ -31,10 +39,10 @@ type Document struct {
 }
 
 func TestTableCreate(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
-		`CREATE TABLE IF NOT EXISTS test.test (fielda int,fieldb bigint,fieldc varint,fieldd varchar,fielde float,fieldf double,fieldg boolean,fieldh timestamp,fieldi uuid,fieldj blob,fieldk counter,PRIMARY KEY (fielda))`,
+		`CREATE TABLE IF NOT EXISTS test.test (fielda int,fieldb bigint,fieldc varint,fieldd varchar,fielde float,fieldf double,fieldg boolean,fieldh timestamp,fieldi uuid,fieldj blob,PRIMARY KEY (fielda))`,
 		[]interface{}(nil),
 	).Return(nil)
 
@@ -46,8 +54,47 @@ func TestTableCreate(t *testing.T) {
 	m.AssertExpectations(t)
 }
 
+func TestTableCreate_counter(t *testing.T) {
+	m := &mock.Mock{}
+	m.On(
+		"Execute",
+		`CREATE TABLE IF NOT EXISTS test.test (clicks counter,id varchar,views counter,PRIMARY KEY (id))`,
+		[]interface{}(nil),
+	).Return(nil)
+
+	k := NewKeyspace(NewMockExecutor(m), "test", nil)
+	tbl := NewTable(k, "test", CounterDocument{}, []string{"id"}, nil, nil)
+	assert.Nil(t, tbl.Create())
+	m.AssertExpectations(t)
+}
+
+func TestNewTable_invalidCounter(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+
+	assert.Panics(t, func() {
+		NewTable(k, "test", struct {
+			ID    string
+			Name  string
+			Views Counter
+		}{}, []string{"id"}, nil, nil)
+	})
+	assert.Panics(t, func() {
+		NewTable(k, "test", CounterDocument{}, []string{"id", "views"}, nil, nil)
+	})
+	assert.NotPanics(t, func() {
+		NewTable(k, "test", CounterDocument{}, []string{"id"}, nil, nil)
+	})
+}
+
+func TestNewTable_noKeyspace(t *testing.T) {
+	assert.NotPanics(t, func() {
+		tbl := NewTable(nil, "test", Document{}, []string{"fielda"}, nil, nil)
+		assert.Equal(t, "test", tbl.Name())
+	})
+}
+
 func TestTableCreate_partitionKey(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		`CREATE TABLE IF NOT EXISTS test.test (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda))`,
@@ -63,7 +110,7 @@ func TestTableCreate_partitionKey(t *testing.T) {
 }
 
 func TestTableCreate_PartitionClustering(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		`CREATE TABLE IF NOT EXISTS test.test (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda,fieldb))`,
@@ -79,7 +126,7 @@ func TestTableCreate_PartitionClustering(t *testing.T) {
 }
 
 func TestTableCreate_multiplePartition(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		`CREATE TABLE IF NOT EXISTS test.test (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY ((fielda,fieldb)))`,
@@ -95,7 +142,7 @@ func TestTableCreate_multiplePartition(t *testing.T) {
 }
 
 func TestTableCreate_multipleClustering(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		`CREATE TABLE IF NOT EXISTS test.test (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda,fieldb,fieldc))`,
@@ -111,7 +158,7 @@ func TestTableCreate_multipleClustering(t *testing.T) {
 }
 
 func TestTableCreate_multipleKeys(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		`CREATE TABLE IF NOT EXISTS test.test (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY ((fielda,fieldb),fieldc,fieldd))`,
@@ -127,7 +174,7 @@ func TestTableCreate_multipleKeys(t *testing.T) {
 }
 
 func TestTableCreate_options(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Execute",
 		`CREATE TABLE IF NOT EXISTS test.test (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda)) WITH COMPACT STORAGE AND CLUSTERING ORDER (fieldb DESC,fieldc ASC)`,
@@ -146,7 +193,7 @@ func TestTableCreate_options(t *testing.T) {
 }
 
 func TestTableSelect_order(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Query",
 		`SELECT * FROM test.test ORDER BY fielda DESC`,
@@ -170,7 +217,7 @@ func TestTableSelect_order(t *testing.T) {
 }
 
 func TestTableSelect_limit(t *testing.T) {
-	m := mock.Mock{}
+	m := &mock.Mock{}
 	m.On(
 		"Query",
 		`SELECT * FROM test.test LIMIT ?`,
@@ -190,3 +237,156 @@ func TestTableSelect_limit(t *testing.T) {
 	assert.Nil(t, err)
 	m.AssertExpectations(t)
 }
+
+func TestTableTruncate(t *testing.T) {
+	m := &mock.Mock{}
+	m.On("Execute", "TRUNCATE TABLE test.test", []interface{}(nil)).Return(nil).Once()
+
+	qe := NewMockExecutor(m)
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewMapTable(k, "test", Document{}, "fielda")
+	assert.Nil(t, tbl.Truncate())
+	assert.Equal(t, ErrFilteredTruncate, tbl.Where(Eq("fielda", "a")).Truncate())
+	m.AssertExpectations(t)
+}
+
+func TestTableRecreate(t *testing.T) {
+	m := &mock.Mock{}
+	mockSchemaAgreement(m)
+	m.On("Execute", "DROP TABLE IF EXISTS test.test", []interface{}(nil)).Return(nil)
+	m.On(
+		"Execute",
+		`CREATE TABLE IF NOT EXISTS test.test (fielda varchar,fieldb varchar,fieldc varchar,fieldd varchar,PRIMARY KEY (fielda))`,
+		[]interface{}(nil),
+	).Return(nil)
+
+	qe := NewMockExecutor(m)
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+	assert.Nil(t, tbl.Recreate())
+	m.AssertExpectations(t)
+}
+
+func TestTableSelect_context(t *testing.T) {
+	m := &mock.Mock{}
+	m.On(
+		"Query",
+		`SELECT * FROM test.test WHERE fielda = ?`,
+		[]interface{}{"a"},
+	).Return([]map[string]interface{}{{"fielda": "a", "fieldb": "b"}}, nil)
+
+	qe := NewMockExecutor(m)
+
+	k := NewKeyspace(qe, "test", nil)
+	tbl := NewTable(k, "test", Document{}, []string{"fielda"}, nil, nil)
+
+	docs := []Document{}
+	err := tbl.Where(Eq("fielda", "a")).Read().ScanContext(context.Background(), &docs)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Document{{FieldA: "a", FieldB: "b"}}, docs)
+	m.AssertExpectations(t)
+}
+
+type accountStatus int
+
+const (
+	accountActive accountStatus = iota + 1
+	accountClosed
+)
+
+func (s accountStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case accountActive:
+		return []byte("active"), nil
+	case accountClosed:
+		return []byte("closed"), nil
+	}
+	return nil, errors.New("Unknown status")
+}
+
+func (s *accountStatus) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "active":
+		*s = accountActive
+	case "closed":
+		*s = accountClosed
+	default:
+		return errors.New("Unknown status")
+	}
+	return nil
+}
+
+type accountAddress struct {
+	City string
+}
+
+func (a accountAddress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"city": a.City})
+}
+
+func (a *accountAddress) UnmarshalJSON(b []byte) error {
+	m := map[string]string{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	a.City = m["city"]
+	return nil
+}
+
+type accountBalance int64
+
+func (b accountBalance) MarshalCQL(info gocql.TypeInfo) ([]byte, error) {
+	return gocql.Marshal(info, int64(b))
+}
+
+func (b *accountBalance) UnmarshalCQL(info gocql.TypeInfo, data []byte) error {
+	var v int64
+	if err := gocql.Unmarshal(info, data, &v); err != nil {
+		return err
+	}
+	*b = accountBalance(v)
+	return nil
+}
+
+type account struct {
+	ID      string
+	Status  accountStatus
+	Address *accountAddress
+	Balance accountBalance `cql:",type=bigint"`
+}
+
+func TestTableMarshalers(t *testing.T) {
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewTable(k, "accounts", account{}, []string{"id"}, nil, nil)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS test.accounts (address varchar,balance bigint,id varchar,status varchar,PRIMARY KEY (id))", tbl.CreateStatement())
+
+	assert.Nil(t, tbl.Set(account{
+		ID:      "a",
+		Status:  accountClosed,
+		Address: &accountAddress{City: "London"},
+		Balance: 100,
+	}).Execute())
+
+	rows, err := k.QueryExecutor().Query(NewQuery(tbl, SelectQueryType))
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "closed", rows[0]["status"])
+		assert.Equal(t, `{"city":"London"}`, rows[0]["address"])
+	}
+
+	doc := account{}
+	assert.Nil(t, tbl.Where(Eq("id", "a")).Read().ScanOne(&doc))
+	assert.Equal(t, account{ID: "a", Status: accountClosed, Address: &accountAddress{City: "London"}, Balance: 100}, doc)
+
+	// Relations are compared with the marshalled value
+	doc = account{}
+	assert.Nil(t, tbl.Where(Eq("id", "a"), Eq("status", accountClosed)).Read().WithOptions(QueryOptions{AllowFiltering: true}).ScanOne(&doc))
+	assert.Equal(t, account{ID: "a", Status: accountClosed, Address: &accountAddress{City: "London"}, Balance: 100}, doc)
+
+	// Balances read back as int64 are unmarshalled with UnmarshalCQL
+	assert.Nil(t, decodeRow(map[string]interface{}{"balance": int64(7)}, &doc))
+	assert.Equal(t, accountBalance(7), doc.Balance)
+}
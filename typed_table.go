@@ -0,0 +1,197 @@
+//go:build go1.18
+// +build go1.18
+
+package gocassa
+
+import (
+	"context"
+
+	"github.com/gocql/gocql"
+)
+
+// TypedTable wraps a Table whose documents are of type T so that documents
+// are written and read as T rather than interface{}, catching type mistakes
+// at compile time. The methods of the embedded Table remain available for
+// anything not covered by the typed API, such as creating the table.
+type TypedTable[T any] struct {
+	*Table
+}
+
+// NewTypedTable creates a table for documents of type T, see NewTable
+func NewTypedTable[T any](
+	keyspace *Keyspace,
+	name string,
+	partitionKeys, clusteringColumns []string,
+	options *TableOptions,
+) *TypedTable[T] {
+	var doc T
+	return &TypedTable[T]{
+		Table: NewTable(keyspace, name, doc, partitionKeys, clusteringColumns, options),
+	}
+}
+
+func (t *TypedTable[T]) Set(v T) RunnableQuery {
+	return t.Table.Set(v)
+}
+
+// List reads every document in the table
+func (t *TypedTable[T]) List(ctx context.Context) ([]T, error) {
+	return scanTyped[T](ctx, t.Table.List())
+}
+
+// Iter returns an iterator over every document in the table
+func (t *TypedTable[T]) Iter(ctx context.Context) *TypedIter[T] {
+	return &TypedIter[T]{iter: t.Table.List().IterContext(ctx)}
+}
+
+func (t *TypedTable[T]) Where(relations ...Relation) *TypedFilteredTable[T] {
+	return &TypedFilteredTable[T]{
+		FilteredTable: t.Table.Where(relations...),
+	}
+}
+
+// TypedFilteredTable is a FilteredTable of documents of type T, see
+// TypedTable.
+type TypedFilteredTable[T any] struct {
+	*FilteredTable
+}
+
+func (t *TypedFilteredTable[T]) Set(v T) RunnableQuery {
+	return t.FilteredTable.Set(v)
+}
+
+// Read reads every document matching the relations
+func (t *TypedFilteredTable[T]) Read(ctx context.Context) ([]T, error) {
+	return scanTyped[T](ctx, t.FilteredTable.Read())
+}
+
+// ReadOne reads the first document matching the relations, gocql.ErrNotFound
+// is returned if there are none.
+func (t *TypedFilteredTable[T]) ReadOne(ctx context.Context) (T, error) {
+	return scanOneTyped[T](ctx, t.FilteredTable.Read())
+}
+
+// Iter returns an iterator over the documents matching the relations
+func (t *TypedFilteredTable[T]) Iter(ctx context.Context) *TypedIter[T] {
+	return &TypedIter[T]{iter: t.FilteredTable.Read().IterContext(ctx)}
+}
+
+func (t *TypedFilteredTable[T]) Where(relations ...Relation) *TypedFilteredTable[T] {
+	t.FilteredTable.Where(relations...)
+	return t
+}
+
+// TypedMapTable wraps a MapTable whose documents are of type T and whose
+// partition key is of type K, see TypedTable.
+type TypedMapTable[K any, T any] struct {
+	*MapTable
+}
+
+// NewTypedMapTable creates a map table for documents of type T, see
+// NewMapTable
+func NewTypedMapTable[K any, T any](keyspace *Keyspace, name string, partitionKey string) *TypedMapTable[K, T] {
+	var doc T
+	return &TypedMapTable[K, T]{
+		MapTable: NewMapTable(keyspace, name, doc, partitionKey),
+	}
+}
+
+func (t *TypedMapTable[K, T]) Set(v T) RunnableQuery {
+	return t.MapTable.Set(v)
+}
+
+func (t *TypedMapTable[K, T]) Update(id K, m map[string]interface{}) RunnableQuery {
+	return t.MapTable.Update(id, m)
+}
+
+func (t *TypedMapTable[K, T]) Delete(id K) RunnableQuery {
+	return t.MapTable.Delete(id)
+}
+
+// Read reads the document with the given id, gocql.ErrNotFound is returned if
+// it does not exist.
+func (t *TypedMapTable[K, T]) Read(ctx context.Context, id K) (T, error) {
+	return scanOneTyped[T](ctx, t.MapTable.Read(id))
+}
+
+// MultiRead reads the documents with the given ids in the same order as ids
+// and returns the ids which were not found, see MapTable.MultiReadContext.
+func (t *TypedMapTable[K, T]) MultiRead(ctx context.Context, ids []K, options *MultiReadOptions) ([]T, []K, error) {
+	keys := make([]interface{}, len(ids))
+	for i, id := range ids {
+		keys[i] = id
+	}
+
+	docs := []T{}
+	missingKeys, err := t.MapTable.MultiReadContext(ctx, keys, &docs, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	missing := make([]K, len(missingKeys))
+	for i, key := range missingKeys {
+		missing[i] = key.(K)
+	}
+
+	return docs, missing, nil
+}
+
+// List reads every document in the table
+func (t *TypedMapTable[K, T]) List(ctx context.Context) ([]T, error) {
+	return scanTyped[T](ctx, t.MapTable.List())
+}
+
+// Iter returns an iterator over every document in the table
+func (t *TypedMapTable[K, T]) Iter(ctx context.Context) *TypedIter[T] {
+	return &TypedIter[T]{iter: t.MapTable.List().IterContext(ctx)}
+}
+
+// TypedIter iterates over the documents of a typed table, fetching further
+// pages as needed.
+type TypedIter[T any] struct {
+	iter Iter
+}
+
+// Next returns the next document, ok is false once there are no more
+// documents or an error occurred. Close returns the error, if any.
+func (it *TypedIter[T]) Next() (doc T, ok bool) {
+	ok = it.iter.Scan(&doc)
+	return doc, ok
+}
+
+// PageState returns the page state of the next page, see Iter.PageState
+func (it *TypedIter[T]) PageState() []byte {
+	return it.iter.PageState()
+}
+
+// Close closes the iterator and returns any error from the query or from
+// decoding the documents.
+func (it *TypedIter[T]) Close() error {
+	return it.iter.Close()
+}
+
+func scanTyped[T any](ctx context.Context, q RunnableQuery) ([]T, error) {
+	docs := []T{}
+	if err := q.ScanContext(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func scanOneTyped[T any](ctx context.Context, q RunnableQuery) (T, error) {
+	var doc T
+	rows, err := q.Executor.QueryContext(ctx, q.Query)
+	if err != nil {
+		return doc, err
+	}
+	if len(rows) == 0 {
+		return doc, gocql.ErrNotFound
+	}
+
+	if err := decodeRow(rows[0], &doc); err != nil {
+		return doc, err
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,72 @@
+//go:build go1.18
+// +build go1.18
+
+package gocassa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedTable(t *testing.T) {
+	ctx := context.Background()
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewTypedTable[memoryEvent](k, "events", []string{"userid"}, []string{"created"}, nil)
+
+	for _, e := range []memoryEvent{{"a", 1, "one"}, {"a", 2, "two"}, {"b", 1, "other"}} {
+		assert.Nil(t, tbl.Set(e).Execute())
+	}
+
+	events, err := tbl.List(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, events, 3)
+
+	events, err = tbl.Where(Eq("userid", "a")).Read(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []memoryEvent{{"a", 1, "one"}, {"a", 2, "two"}}, events)
+
+	event, err := tbl.Where(Eq("userid", "a")).Where(Eq("created", 2)).ReadOne(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, memoryEvent{"a", 2, "two"}, event)
+
+	_, err = tbl.Where(Eq("userid", "x")).ReadOne(ctx)
+	assert.Equal(t, gocql.ErrNotFound, err)
+
+	iter := tbl.Where(Eq("userid", "a")).Iter(ctx)
+	names := []string{}
+	for e, ok := iter.Next(); ok; e, ok = iter.Next() {
+		names = append(names, e.Name)
+	}
+	assert.Nil(t, iter.Close())
+	assert.Equal(t, []string{"one", "two"}, names)
+}
+
+func TestTypedMapTable(t *testing.T) {
+	ctx := context.Background()
+	k := NewKeyspace(NewMemoryExecutor(), "test", nil)
+	tbl := NewTypedMapTable[string, *memoryDocument](k, "docs", "id")
+
+	assert.Nil(t, tbl.Set(&memoryDocument{ID: "1", Amount: 1}).Execute())
+	assert.Nil(t, tbl.Set(&memoryDocument{ID: "2", Amount: 2}).Execute())
+	assert.Nil(t, tbl.Update("2", map[string]interface{}{"amount": 3}).Execute())
+
+	doc, err := tbl.Read(ctx, "2")
+	assert.Nil(t, err)
+	assert.Equal(t, &memoryDocument{ID: "2", Amount: 3}, doc)
+
+	docs, missing, err := tbl.MultiRead(ctx, []string{"2", "3", "1"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3"}, missing)
+	if assert.Len(t, docs, 2) {
+		assert.Equal(t, "2", docs[0].ID)
+		assert.Equal(t, "1", docs[1].ID)
+	}
+
+	assert.Nil(t, tbl.Delete("1").Execute())
+	docs, err = tbl.List(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, docs, 1)
+}